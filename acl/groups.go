@@ -0,0 +1,78 @@
+// Package acl resolves who can read a document from the permissions Drive
+// reports for it.
+package acl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Groups maps each group's email address to the addresses of its members,
+// which may themselves be groups. Drive does not say who is in a group, so
+// the mapping is kept in a local file.
+type Groups map[string][]string
+
+// LoadGroups reads a JSON mapping file such as
+//
+//	{
+//	  "eng@example.com": ["alice@example.com", "sre@example.com"],
+//	  "sre@example.com": ["bob@example.com"]
+//	}
+func LoadGroups(path string) (Groups, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read groups file: %w", err)
+	}
+
+	var raw map[string][]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse groups file %s: %w", path, err)
+	}
+
+	groups := make(Groups, len(raw))
+	for group, members := range raw {
+		key := normalize(group)
+		for _, m := range members {
+			groups[key] = append(groups[key], normalize(m))
+		}
+	}
+	return groups, nil
+}
+
+// Memberships returns every group email belongs to, directly or through
+// other groups, sorted.
+func (g Groups) Memberships(email string) []string {
+	parents := make(map[string][]string)
+	for group, members := range g {
+		for _, m := range members {
+			parents[m] = append(parents[m], group)
+		}
+	}
+
+	seen := make(map[string]bool)
+	queue := []string{normalize(email)}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, group := range parents[next] {
+			if !seen[group] {
+				seen[group] = true
+				queue = append(queue, group)
+			}
+		}
+	}
+
+	out := make([]string, 0, len(seen))
+	for group := range seen {
+		out = append(out, group)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func normalize(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
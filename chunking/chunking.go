@@ -0,0 +1,156 @@
+package chunking
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"injestion-pipeline/models"
+)
+
+const breadcrumbSeparator = " > "
+
+// Options bounds the size of each chunk. Sections longer than MaxTokens
+// words are cut into windows that repeat the last Overlap words of the
+// previous window.
+type Options struct {
+	MaxTokens int
+	Overlap   int
+}
+
+var DefaultOptions = Options{
+	MaxTokens: 200,
+	Overlap:   40,
+}
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	fencePattern   = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+type section struct {
+	headings []string
+	body     string
+}
+
+// Split cuts a document into chunks. Markdown is split along its heading
+// hierarchy, so every chunk knows which section it came from; other text is
+// cut into overlapping token windows.
+func Split(content string, extension string, opts Options) []models.Chunk {
+	var sections []section
+	if extension == ".md" {
+		sections = markdownSections(content)
+	} else {
+		sections = []section{{body: content}}
+	}
+
+	var chunks []models.Chunk
+	for _, s := range sections {
+		heading := strings.Join(s.headings, breadcrumbSeparator)
+		for _, window := range windows(s.body, opts) {
+			chunks = append(chunks, models.Chunk{
+				Ordinal: len(chunks),
+				Heading: heading,
+				Content: window,
+			})
+		}
+	}
+
+	return chunks
+}
+
+// markdownSections splits content at ATX headings, ignoring anything that
+// looks like a heading inside a fenced code block. Each section keeps the
+// path of headings above it. Headings with no text of their own before the
+// next heading do not become sections.
+func markdownSections(content string) []section {
+	var sections []section
+	var stack []string
+	var levels []int
+	var headingLine string
+	var body strings.Builder
+	inFence := false
+
+	flush := func() {
+		if strings.TrimSpace(body.String()) != "" {
+			sections = append(sections, section{
+				headings: append([]string(nil), stack...),
+				body:     headingLine + body.String(),
+			})
+		}
+		body.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if fencePattern.MatchString(line) {
+			inFence = !inFence
+		}
+
+		m := headingPattern.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		if m == nil || inFence {
+			body.WriteString(line)
+			continue
+		}
+
+		flush()
+
+		level := len(m[1])
+		for len(levels) > 0 && levels[len(levels)-1] >= level {
+			levels = levels[:len(levels)-1]
+			stack = stack[:len(stack)-1]
+		}
+		levels = append(levels, level)
+		stack = append(stack, m[2])
+		headingLine = line
+	}
+	flush()
+
+	return sections
+}
+
+// windows cuts text into pieces of at most opts.MaxTokens words. Each piece
+// is a slice of the original text, so line breaks and spacing survive.
+func windows(text string, opts Options) []string {
+	words := wordBounds(text)
+	if len(words) == 0 {
+		return nil
+	}
+
+	maxTokens := max(opts.MaxTokens, 1)
+	step := max(maxTokens-opts.Overlap, 1)
+
+	var out []string
+	for start := 0; ; start += step {
+		end := min(start+maxTokens, len(words))
+		out = append(out, text[words[start][0]:words[end-1][1]])
+		if end == len(words) {
+			break
+		}
+	}
+
+	return out
+}
+
+func wordBounds(text string) [][2]int {
+	var words [][2]int
+	start := -1
+
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				words = append(words, [2]int{start, i})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		words = append(words, [2]int{start, len(text)})
+	}
+
+	return words
+}
@@ -2,8 +2,14 @@ package cmd
 
 const (
 	DEFAULT_DB_PATH              = "knowledge.db"
+	DEFAULT_GROUPS_PATH          = "groups.json"
+	DEFAULT_REDACTION_PATH       = "redaction.json"
+	DEFAULT_REDACTION_REPORT     = "redaction-report.json"
 	FOLDER_MIME_TYPE             = "application/vnd.google-apps.folder"
 	MD_MIME_TYPE                 = "text/markdown"
 	TXT_MIME_TYPE                = "text/plain"
 	INGESTION_PIPELINE_FOLDER_ID = "16RWlHvc-TKdqpBYDJMQdt319BS7AvjxM"
+	EMBEDDER_HASH                = "hash"
+	EMBEDDER_HTTP                = "http"
+	EMBEDDER_NONE                = "none"
 )
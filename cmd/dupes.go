@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"injestion-pipeline/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	dupesThreshold float64
+)
+
+var dupesCmd = &cobra.Command{
+	Use:   "dupes",
+	Short: "Report clusters of near-duplicate documents",
+	Long: `Groups documents whose SimHash fingerprints are at least --threshold similar,
+such as "Copy of" files, v2 drafts and final-final versions. Each cluster lists
+its newest document first, which is the one 'search --collapse-dupes' keeps, and
+the documents at least --threshold similar to it.
+
+Documents are put in clusters as they are saved, joining the cluster of the
+most similar document stored before them when it is at least 0.8 similar, so
+--threshold cannot be lower than that. Documents of fewer than 10 words are too
+short to compare and are never counted as duplicates.`,
+	Args: cobra.NoArgs,
+	RunE: runDupes,
+}
+
+func init() {
+	dupesCmd.Flags().Float64Var(&dupesThreshold, "threshold", storage.DefaultDuplicateThreshold, "Minimum similarity, from 0 to 1, for two documents to count as duplicates")
+}
+
+func runDupes(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if dupesThreshold <= 0 || dupesThreshold > 1 {
+		return fmt.Errorf("--threshold must be between 0 and 1")
+	}
+
+	db := storage.NewSQLiteDB(DEFAULT_DB_PATH)
+	if err := db.Initialize(); err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	clusters, err := db.NearDuplicates(ctx, dupesThreshold)
+	if err != nil {
+		return fmt.Errorf("Failed to find duplicates: %w", err)
+	}
+
+	if len(clusters) == 0 {
+		fmt.Printf("No near-duplicates found at threshold %.2f\n", dupesThreshold)
+		return nil
+	}
+
+	redundant := 0
+	for i, cluster := range clusters {
+		fmt.Printf("Cluster %d (%d documents)\n", i+1, len(cluster.Members))
+		for j, m := range cluster.Members {
+			marker := "  keep"
+			if j > 0 {
+				marker = fmt.Sprintf("  %.2f", m.Similarity)
+				redundant++
+			}
+			fmt.Printf("%s  [%d] %s  %s  %d bytes\n", marker, m.DocumentID, m.Path, m.Modified, m.SizeBytes)
+		}
+		fmt.Println()
+	}
+
+	log.Printf("INFO: %d cluster(s), %d document(s) with a newer near-duplicate\n", len(clusters), redundant)
+	return nil
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"injestion-pipeline/embedding"
+)
+
+// newEmbedder builds the embedder selected by the --embedder flags. It
+// returns nil for "none". The HTTP embedder reads its API key from the
+// EMBEDDING_API_KEY environment variable.
+func newEmbedder() (embedding.Embedder, error) {
+	switch embedderKind {
+	case EMBEDDER_HASH:
+		return embedding.NewHashEmbedder(embedding.DefaultHashDimensions), nil
+	case EMBEDDER_HTTP:
+		return embedding.NewHTTPEmbedder(embedding.HTTPConfig{
+			BaseURL: embeddingURL,
+			Model:   embeddingModel,
+			APIKey:  os.Getenv("EMBEDDING_API_KEY"),
+		})
+	case EMBEDDER_NONE:
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("Unknown embedder %q, expected %s, %s or %s", embedderKind, EMBEDDER_HASH, EMBEDDER_HTTP, EMBEDDER_NONE)
+}
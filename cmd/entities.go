@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"injestion-pipeline/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	entitiesType string
+	entitiesTop  int
+)
+
+var entitiesCmd = &cobra.Command{
+	Use:   "entities",
+	Short: "Report the entities mentioned across documents",
+	Long: `Lists the ticket keys, email addresses, hostnames, URLs and custom entities
+found at ingest, by the number of documents that mention them and then by
+mentions in total. Search for the documents naming one with
+'search entity:<type>=<value>'.
+
+Entities are redacted like the documents they come from. Email addresses are
+masked at ingest by default, so none are listed unless the redaction config sets
+the email detector to flag or off (see 'ingest --help').
+
+Examples:
+  pipeline entities --type email --top 50
+  pipeline entities --type jira`,
+	Args: cobra.NoArgs,
+	RunE: runEntities,
+}
+
+func init() {
+	entitiesCmd.Flags().StringVarP(&entitiesType, "type", "t", "", "Only list entities of this type, such as jira, email, hostname or url")
+	entitiesCmd.Flags().IntVar(&entitiesTop, "top", 20, "Maximum number of entities to list")
+}
+
+func runEntities(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if entitiesTop < 1 {
+		return fmt.Errorf("--top must be at least 1")
+	}
+
+	db := storage.NewSQLiteDB(DEFAULT_DB_PATH)
+	if err := db.Initialize(); err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	rows, err := db.TopEntities(ctx, strings.ToLower(entitiesType), entitiesTop)
+	if err != nil {
+		return fmt.Errorf("Failed to list entities: %w", err)
+	}
+
+	if len(rows) == 0 {
+		if entitiesType != "" {
+			fmt.Printf("No %s entities found\n", entitiesType)
+		} else {
+			fmt.Println("No entities found")
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DOCS\tMENTIONS\tTYPE\tVALUE")
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\n", row.Documents, row.Mentions, row.Type, row.Value)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"injestion-pipeline/storage"
+)
+
+// markStyle is the pair of strings wrapped around every matched term when a
+// fragment is printed.
+type markStyle struct {
+	open  string
+	close string
+}
+
+var (
+	plainMarks    = markStyle{}
+	ansiMarks     = markStyle{open: "\x1b[1;33m", close: "\x1b[0m"}
+	markdownMarks = markStyle{open: "**", close: "**"}
+)
+
+func (m markStyle) render(f storage.Fragment) string {
+	if m.open == "" && m.close == "" {
+		return f.Text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, h := range f.Highlights {
+		b.WriteString(f.Text[last:h.Start])
+		b.WriteString(m.open)
+		b.WriteString(f.Text[h.Start:h.End])
+		b.WriteString(m.close)
+		last = h.End
+	}
+	b.WriteString(f.Text[last:])
+
+	return b.String()
+}
+
+// terminalMarks picks ANSI colour when stdout is a terminal and NO_COLOR is
+// unset, and no marks at all otherwise.
+func terminalMarks() markStyle {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return plainMarks
+	}
+
+	info, err := os.Stdout.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return plainMarks
+	}
+
+	return ansiMarks
+}
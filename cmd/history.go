@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/diff"
+	"injestion-pipeline/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffContext int
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history <id|path>",
+	Short: "List the stored versions of a document",
+	Long: `Lists every version of a document kept by ingest, oldest first. A new
+version is stored whenever the content changes, and ingest --revisions adds the
+revisions Drive keeps; ingest --keep-versions sets how many are kept per
+document.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHistory,
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <id|path> [from-version] [to-version]",
+	Short: "Show what changed between two versions of a document",
+	Long: `Prints a unified diff between two stored versions of a document. Versions
+are numbered as in 'pipeline history' and may be written as 3 or v3.
+
+With no versions the latest change is shown; with one version, the diff runs
+from it to the current content.
+
+Examples:
+  pipeline diff /policies/leave.md
+  pipeline diff /policies/leave.md v2
+  pipeline diff 42 1 4`,
+	Args: cobra.RangeArgs(1, 3),
+	RunE: runDiff,
+}
+
+func init() {
+	diffCmd.Flags().IntVarP(&diffContext, "context", "U", 3, "Number of unchanged lines to show around each change")
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	db := storage.NewSQLiteDB(DEFAULT_DB_PATH)
+	if err := db.Initialize(); err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	doc, err := db.FindDocument(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("Failed to find document: %w", err)
+	}
+
+	versions, err := db.DocumentHistory(ctx, doc.DriveFileID)
+	if err != nil {
+		return fmt.Errorf("Failed to load history: %w", err)
+	}
+
+	if len(versions) == 0 {
+		fmt.Printf("No versions stored for %s yet; one is kept the next time it is ingested\n", doc.Filepath)
+		return nil
+	}
+
+	fmt.Printf("History of %s (%d version(s), oldest first):\n\n", doc.Filepath, len(versions))
+	for i, v := range versions {
+		current := ""
+		if i == len(versions)-1 {
+			current = "  (current)"
+		}
+		fmt.Printf("v%-3d modified %s  saved %s  %d bytes  %s%s\n", v.Version, v.LastModified, v.SavedAt, v.SizeBytes, v.Md5Checksum, current)
+		if v.Filepath != doc.Filepath {
+			fmt.Printf("     at %s\n", v.Filepath)
+		}
+		if v.RevisionID != "" {
+			fmt.Printf("     Drive revision %s by %s\n", v.RevisionID, v.Author)
+		}
+	}
+
+	return nil
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if diffContext < 0 {
+		return fmt.Errorf("--context cannot be negative")
+	}
+
+	db := storage.NewSQLiteDB(DEFAULT_DB_PATH)
+	if err := db.Initialize(); err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	doc, err := db.FindDocument(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("Failed to find document: %w", err)
+	}
+
+	versions, err := db.DocumentHistory(ctx, doc.DriveFileID)
+	if err != nil {
+		return fmt.Errorf("Failed to load history: %w", err)
+	}
+	if len(versions) == 0 {
+		return fmt.Errorf("%s has no stored versions; it was saved before versions were kept, run ingest --refetch to start its history", doc.Filepath)
+	}
+	if len(versions) < 2 && len(args) == 1 {
+		fmt.Printf("%s has %d stored version(s), nothing to compare\n", doc.Filepath, len(versions))
+		return nil
+	}
+
+	var from, to int64
+	switch len(args) {
+	case 1:
+		from = versions[len(versions)-2].Version
+		to = versions[len(versions)-1].Version
+	case 2:
+		if from, err = parseVersion(args[1]); err != nil {
+			return err
+		}
+		to = versions[len(versions)-1].Version
+	case 3:
+		if from, err = parseVersion(args[1]); err != nil {
+			return err
+		}
+		if to, err = parseVersion(args[2]); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range []int64{from, to} {
+		if !hasVersion(versions, v) {
+			return fmt.Errorf("%s has no version v%d; stored versions are %s", doc.Filepath, v, listVersions(versions))
+		}
+	}
+
+	older, err := db.DocumentVersion(ctx, doc.DriveFileID, from)
+	if err != nil {
+		return fmt.Errorf("Failed to load version: %w", err)
+	}
+	newer, err := db.DocumentVersion(ctx, doc.DriveFileID, to)
+	if err != nil {
+		return fmt.Errorf("Failed to load version: %w", err)
+	}
+
+	out := diff.Unified(older.Content, newer.Content,
+		fmt.Sprintf("%s\tv%d %s", older.Filepath, older.Version, older.LastModified),
+		fmt.Sprintf("%s\tv%d %s", newer.Filepath, newer.Version, newer.LastModified),
+		diffContext)
+	if out == "" {
+		fmt.Printf("v%d and v%d have the same content\n", from, to)
+		return nil
+	}
+
+	fmt.Print(out)
+	return nil
+}
+
+func hasVersion(versions []pipeline.ListDocumentVersionsRow, version int64) bool {
+	for _, v := range versions {
+		if v.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// listVersions names the stored versions, as in "v3, v4, v5".
+func listVersions(versions []pipeline.ListDocumentVersionsRow) string {
+	names := make([]string, 0, len(versions))
+	for _, v := range versions {
+		names = append(names, fmt.Sprintf("v%d", v.Version))
+	}
+	return strings.Join(names, ", ")
+}
+
+func parseVersion(arg string) (int64, error) {
+	v, err := strconv.ParseInt(strings.TrimPrefix(strings.ToLower(arg), "v"), 10, 64)
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("Invalid version %q, expected a number such as 3 or v3", arg)
+	}
+	return v, nil
+}
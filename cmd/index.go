@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"injestion-pipeline/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	indexSamples int
+	indexK       int
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manage the approximate nearest neighbour index used by semantic search",
+	Long: `Semantic search uses an HNSW index stored next to the database (knowledge.db.hnsw).
+It is kept up to date as documents are ingested and cleared. When the file is
+missing or unreadable, searches scan every vector and the next ingest builds it
+again; it is compacted when replaced chunks make up a quarter of it. Rebuild it
+after switching embedders, and check its recall with stats.`,
+}
+
+var indexRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Rebuild the index from the vectors stored for the current embedder",
+	Args:  cobra.NoArgs,
+	RunE:  runIndexRebuild,
+}
+
+var indexStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show index size, recall and latency against an exact scan",
+	Args:  cobra.NoArgs,
+	RunE:  runIndexStats,
+}
+
+func init() {
+	indexStatsCmd.Flags().IntVar(&indexSamples, "samples", 100, "Number of stored vectors to use as test queries")
+	indexStatsCmd.Flags().IntVar(&indexK, "k", 10, "Number of neighbours to compare for recall@k")
+
+	indexCmd.AddCommand(indexRebuildCmd)
+	indexCmd.AddCommand(indexStatsCmd)
+}
+
+func runIndexRebuild(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	embedder, err := newEmbedder()
+	if err != nil {
+		return err
+	}
+	if embedder == nil {
+		return fmt.Errorf("Rebuilding the index needs an embedder, got --embedder=%s", EMBEDDER_NONE)
+	}
+
+	db := storage.NewSQLiteDB(DEFAULT_DB_PATH)
+	if err := db.Initialize(); err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	count, err := db.RebuildVectorIndex(ctx, embedder.Model())
+	if err != nil {
+		return fmt.Errorf("Failed to rebuild index: %w", err)
+	}
+
+	log.Printf("INFO: Indexed %d vectors for model %s\n", count, embedder.Model())
+	return nil
+}
+
+func runIndexStats(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	db := storage.NewSQLiteDB(DEFAULT_DB_PATH)
+	if err := db.Initialize(); err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	stats, err := db.VectorIndexStats(ctx, indexSamples, indexK)
+	if err != nil {
+		return fmt.Errorf("Failed to read index stats: %w", err)
+	}
+
+	fmt.Printf("Index:    %s (%d bytes)\n", stats.Path, stats.FileBytes)
+	fmt.Printf("Model:    %s, %d dimensions\n", stats.Model, stats.Dims)
+	fmt.Printf("Vectors:  %d indexed, %d removed, %d stored\n", stats.Vectors, stats.Deleted, stats.Stored)
+	if stats.Vectors != stats.Stored || stats.Deleted > 0 {
+		fmt.Println("          run 'pipeline index rebuild' to bring the index in line with the database")
+	}
+
+	if stats.Samples == 0 {
+		return nil
+	}
+
+	fmt.Printf("Recall@%d: %.3f over %d queries\n", stats.K, stats.Recall, stats.Samples)
+	fmt.Printf("Latency:  %s per query (exact scan %s)\n", stats.IndexLatency, stats.ExactLatency)
+
+	return nil
+}
@@ -2,12 +2,22 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"strings"
 
 	"injestion-pipeline/auth"
+	"injestion-pipeline/chunking"
+	"injestion-pipeline/embedding"
+	"injestion-pipeline/entities"
 	"injestion-pipeline/ingestion"
+	"injestion-pipeline/language"
+	"injestion-pipeline/models"
+	"injestion-pipeline/redact"
 	"injestion-pipeline/storage"
+	"injestion-pipeline/summary"
 
 	"github.com/spf13/cobra"
 	"google.golang.org/api/drive/v3"
@@ -15,7 +25,14 @@ import (
 )
 
 var (
-	folderID string
+	folderID           string
+	ingestRefetch      bool
+	ingestKeepVersions int
+	ingestRevisions    bool
+	ingestComments     bool
+	ingestEntities     []string
+	ingestRedaction    string
+	ingestReport       string
 )
 
 var ingestCmd = &cobra.Command{
@@ -24,13 +41,71 @@ var ingestCmd = &cobra.Command{
 	Long: `Recursively traverse a Google Drive folder and ingest all .txt and .md files.
 
 The folder ID can be found in the Google Drive URL:
-https://drive.google.com/drive/folders/FOLDER_ID_HERE`,
+https://drive.google.com/drive/folders/FOLDER_ID_HERE
+
+Files whose md5Checksum and path match the stored copy are not downloaded
+again; use --refetch to download everything.
+
+With --revisions the past revisions Drive keeps of each file are downloaded
+too and stored as versions, with their author and time, for 'history',
+'diff' and 'search --as-of'. Every file is downloaded in this mode.
+
+With --comments the comment threads on each file are stored as well, for
+'search --comments'. Comments can change without the file changing, so every
+file is downloaded in this mode too.
+
+Ticket keys, email addresses, hostnames and URLs are extracted from every
+document for 'search entity:' and 'entities'. Add more entity types with
+--entity-pattern type=regexp; the first group of the regexp, if any, is the
+entity.
+
+The language of each document is detected, and documents in English, German
+or Ukrainian are also indexed by word stem for 'search' and 'search lang:'.
+Documents stored before this was added are neither; use --refetch to index
+them again.
+
+Each document also gets a summary of the sentences that best sum it up, and
+its keywords: the terms that set it apart from the other documents. Keywords
+are worked out again for every document at the end of each run, as the
+documents stored change. 'search' and 'list --long' show them.
+
+Before anything is stored, AWS keys, JWTs, private keys, card numbers and email
+addresses are masked as [REDACTED:<detector>]. The --redaction file can set a
+detector to drop the document instead, to flag it while storing it unchanged,
+or to off, and can add custom regexp rules:
+
+  {
+    "detectors": {"email": "flag", "private_key": "drop"},
+    "rules": [{"name": "customer_id", "pattern": "CUST-[0-9]{6}", "mode": "mask"}]
+  }
+
+Besides the text, the detectors run over comment and revision authors, owners,
+the last modifying user and app properties, so with email addresses masked
+'search owner:' matches owners by name only. The name and path of a file are
+never masked, since they identify it, so a mask match there is flagged instead;
+sharing permissions are kept as they are for 'search --as'.
+
+Entities go through the same detectors, and one with a masked match is not
+stored. Email addresses are masked by default, so 'entities --type email' and
+'search entity:email=...' find nothing unless the --redaction file sets email
+to flag or off.
+
+Each run writes what was found, by detector and line but never the matched
+text, to the --redaction-report file. Unchanged files are not downloaded, so
+use --refetch to redact documents stored before a rule was added.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runIngest,
 }
 
 func init() {
 	ingestCmd.Flags().StringVarP(&folderID, "folder", "f", "", "Google Drive folder ID")
+	ingestCmd.Flags().IntVar(&ingestKeepVersions, "keep-versions", storage.DefaultVersionLimit, "Versions of each document to keep for history and diff; 0 keeps all")
+	ingestCmd.Flags().BoolVar(&ingestRevisions, "revisions", false, "Also fetch each file's Drive revision history and store it as versions")
+	ingestCmd.Flags().BoolVar(&ingestComments, "comments", false, "Also fetch the comments and replies on each file")
+	ingestCmd.Flags().StringArrayVar(&ingestEntities, "entity-pattern", nil, "Extract entities of another type, written as type=regexp; may be repeated")
+	ingestCmd.Flags().StringVar(&ingestRedaction, "redaction", DEFAULT_REDACTION_PATH, "JSON file of redaction modes and custom rules")
+	ingestCmd.Flags().StringVar(&ingestReport, "redaction-report", DEFAULT_REDACTION_REPORT, "File to write the redaction report to")
+	ingestCmd.Flags().BoolVar(&ingestRefetch, "refetch", false, "Download every file, even when its checksum shows it is unchanged")
 }
 
 func runIngest(cmd *cobra.Command, args []string) error {
@@ -40,7 +115,12 @@ func runIngest(cmd *cobra.Command, args []string) error {
 	if err := db.Initialize(); err != nil {
 		return fmt.Errorf("Failed to initialize database: %w", err)
 	}
-	defer db.Close()
+	db.KeepVersions(ingestKeepVersions)
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Printf("WARNING: %v\n", err)
+		}
+	}()
 
 	authenticator, err := auth.NewGoogleAuthenticator(auth.Config{
 		CredentialsPath: credentialsPath,
@@ -61,12 +141,52 @@ func runIngest(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Unable to retrieve Drive client: %w", err)
 	}
 
+	embedder, err := newEmbedder()
+	if err != nil {
+		return err
+	}
+
+	redactor, err := loadRedactor(ingestRedaction, cmd.Flags().Changed("redaction"))
+	if err != nil {
+		return err
+	}
+	report := redact.NewReport()
+
+	extractors := entities.Builtin()
+	for _, spec := range ingestEntities {
+		pattern, err := entities.ParsePattern(spec)
+		if err != nil {
+			return fmt.Errorf("Invalid --entity-pattern: %w", err)
+		}
+		extractors = append(extractors, pattern)
+	}
+
 	if folderID == "" {
 		folderID = INGESTION_PIPELINE_FOLDER_ID
 	}
 
 	di := ingestion.NewDriveIngester(service)
 
+	if ingestRevisions {
+		di.FetchRevisions()
+	}
+
+	if ingestComments {
+		di.FetchComments()
+	}
+
+	if !ingestRefetch && !ingestRevisions && !ingestComments {
+		hashes, err := db.ListContentHashes(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed to load content hashes: %w", err)
+		}
+		known := make(map[string]ingestion.KnownFile, len(hashes))
+		for _, h := range hashes {
+			known[h.DriveFileID] = ingestion.KnownFile{Path: h.Filepath, MD5Checksum: h.Md5Checksum}
+		}
+		di.SkipUnchanged(known)
+	}
+
 	documents, err := di.IngestFolder(folderID, "/")
 	if err != nil {
 		return fmt.Errorf("Failed to ingest folder '%s': %w\n", folderID, err)
@@ -75,6 +195,34 @@ func runIngest(cmd *cobra.Command, args []string) error {
 	log.Printf("INFO: Saving %d documents to database...\n", len(documents))
 	saved := 0
 	for _, document := range documents {
+		// Entities are extracted before redaction, so that they are
+		// redacted like the rest of the document rather than cut apart
+		// by the markers.
+		document.Entities = entities.Extract(document.Content, extractors)
+
+		result := redactor.Document(document)
+		report.Add(document.DriveFileID, document.FilePath, result)
+		if result.Dropped {
+			log.Printf("WARNING: Dropped %s: %s\n", document.FilePath, describeFindings(result.Findings))
+			if err := db.DeleteDocument(ctx, document.DriveFileID); err != nil {
+				return fmt.Errorf("Failed to remove dropped document %s: %w", document.FilePath, err)
+			}
+			continue
+		}
+		if action := result.Action(); action != "" {
+			log.Printf("INFO: Redaction %s %s: %s\n", action, document.FilePath, describeFindings(result.Findings))
+		}
+
+		document.Chunks = chunking.Split(document.Content, document.Extension, chunking.DefaultOptions)
+		document.Language = language.Detect(document.Content)
+		document.Summary = summary.Summarize(document.Content, summary.DefaultSentences)
+
+		if embedder != nil {
+			if err := embedChunks(ctx, embedder, document); err != nil {
+				return fmt.Errorf("Failed to embed document %s: %w", document.FileName, err)
+			}
+		}
+
 		err := db.SaveDocument(ctx, document)
 		if err != nil {
 			return fmt.Errorf("Warning: Failed to save document %s: %w\n", document.FileName, err)
@@ -84,7 +232,93 @@ func runIngest(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// The content of unchanged files was redacted when it was stored, but
+	// their metadata is stored again on every run.
+	for _, file := range di.Unchanged {
+		result := redactor.Metadata(file.Metadata)
+		report.Add(file.DriveFileID, file.Path, result)
+		if result.Dropped {
+			log.Printf("WARNING: Dropped %s: %s\n", file.Path, describeFindings(result.Findings))
+			if err := db.DeleteDocument(ctx, file.DriveFileID); err != nil {
+				return fmt.Errorf("Failed to remove dropped document %s: %w", file.Path, err)
+			}
+			continue
+		}
+		if action := result.Action(); action != "" {
+			log.Printf("INFO: Redaction %s %s: %s\n", action, file.Path, describeFindings(result.Findings))
+		}
+
+		if err := db.UpdateMetadata(ctx, file.DriveFileID, file.Metadata); err != nil {
+			log.Printf("WARNING: Failed to update metadata of %s: %v\n", file.DriveFileID, err)
+		}
+	}
+
+	if err := db.RefreshKeywords(ctx); err != nil {
+		return fmt.Errorf("Failed to update keywords: %w", err)
+	}
+
+	if err := report.Write(ingestReport); err != nil {
+		log.Printf("WARNING: %v\n", err)
+	}
+
 	log.Printf("Ingestion complete! Saved %d/%d documents to database.\n", saved, len(documents))
+	log.Printf("INFO: Downloaded %d file(s), %d bytes; skipped %d unchanged file(s), %d bytes saved\n",
+		di.Stats.Downloaded, di.Stats.BytesDownloaded, di.Stats.Skipped, di.Stats.BytesSaved)
+	if ingestRevisions {
+		log.Printf("INFO: Fetched %d past revision(s)\n", di.Stats.Revisions)
+	}
+	if ingestComments {
+		log.Printf("INFO: Fetched %d comment(s) and replies\n", di.Stats.Comments)
+	}
+	log.Printf("INFO: Redaction masked %d, dropped %d and flagged %d document(s); report written to %s\n",
+		report.Masked, report.Dropped, report.Flagged, ingestReport)
 	log.Printf("Use './pipeline search <query>' to search the knowledge base\n")
 	return nil
 }
+
+// embedChunks attaches a vector to every chunk of doc. The heading
+// breadcrumb is embedded along with the text so a chunk keeps the context
+// of the section it came from.
+func embedChunks(ctx context.Context, embedder embedding.Embedder, doc *models.Document) error {
+	texts := make([]string, len(doc.Chunks))
+	for i, chunk := range doc.Chunks {
+		texts[i] = chunk.Heading + "\n\n" + chunk.Content
+	}
+
+	vectors, err := embedder.Embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+
+	for i := range doc.Chunks {
+		doc.Chunks[i].Vector = vectors[i]
+	}
+	doc.EmbeddingModel = embedder.Model()
+
+	return nil
+}
+
+// loadRedactor builds the redaction stage from the config file at path. A
+// missing file gives the built-in detectors, unless it was asked for.
+func loadRedactor(path string, explicit bool) (*redact.Redactor, error) {
+	config, err := redact.LoadConfig(path)
+	if err != nil && (explicit || !errors.Is(err, fs.ErrNotExist)) {
+		return nil, fmt.Errorf("Failed to load redaction config: %w", err)
+	}
+
+	detectors, err := config.Build()
+	if err != nil {
+		return nil, fmt.Errorf("Invalid redaction config: %w", err)
+	}
+	return redact.New(detectors), nil
+}
+
+// describeFindings summarises findings for the log, as in
+// "2 aws_access_key in content, 1 email in comment abc".
+func describeFindings(findings []redact.Finding) string {
+	parts := make([]string, 0, len(findings))
+	for _, f := range findings {
+		parts = append(parts, fmt.Sprintf("%d %s in %s", f.Count, f.Detector, f.Field))
+	}
+	return strings.Join(parts, ", ")
+}
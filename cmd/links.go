@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"injestion-pipeline/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var linksCmd = &cobra.Command{
+	Use:   "links <id|path>",
+	Short: "List the documents and URLs a document links to",
+	Long: `Lists the markdown links, [[wiki links]] and Drive URLs in a document, in
+the order they appear, with the stored document each one resolves to.
+
+Drive URLs resolve by the file id they contain, relative links by the path
+they point at from the document's folder, and wiki links by file name, with or
+without its extension. Links are read at ingest, so documents ingested before
+links were tracked have none until they are ingested again.
+
+Examples:
+  pipeline links /eng/runbooks/failover.md
+  pipeline links 42`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLinks,
+}
+
+var backlinksCmd = &cobra.Command{
+	Use:   "backlinks <id|path>",
+	Short: "List the documents that link to a document",
+	Long: `Lists the documents whose links resolve to the given one, resolved the same
+way as in 'pipeline links'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBacklinks,
+}
+
+var orphansCmd = &cobra.Command{
+	Use:   "orphans",
+	Short: "Report documents that no other document links to",
+	Long: `Lists the documents that no other document links to, by path. Links from a
+document to itself do not count.`,
+	Args: cobra.NoArgs,
+	RunE: runOrphans,
+}
+
+func runLinks(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	db := storage.NewSQLiteDB(DEFAULT_DB_PATH)
+	if err := db.Initialize(); err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	doc, err := db.FindDocument(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("Failed to find document: %w", err)
+	}
+
+	links, err := db.OutboundLinks(ctx, doc.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to load links: %w", err)
+	}
+
+	if len(links) == 0 {
+		fmt.Printf("No links found in %s\n", doc.Filepath)
+		return nil
+	}
+
+	fmt.Printf("Links in %s:\n\n", doc.Filepath)
+	unresolved := 0
+	for _, link := range links {
+		if link.TargetID != 0 {
+			fmt.Printf("  [%d] %s\n", link.TargetID, link.TargetFilepath)
+		} else {
+			fmt.Printf("  (not stored) %s\n", link.Target)
+			unresolved++
+		}
+		fmt.Printf("       %s link %q\n", link.Kind, linkText(link.Text, link.Target))
+	}
+	fmt.Println()
+
+	log.Printf("INFO: %d link(s), %d not resolved to a stored document\n", len(links), unresolved)
+	return nil
+}
+
+func runBacklinks(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	db := storage.NewSQLiteDB(DEFAULT_DB_PATH)
+	if err := db.Initialize(); err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	doc, err := db.FindDocument(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("Failed to find document: %w", err)
+	}
+
+	links, err := db.Backlinks(ctx, doc.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to load backlinks: %w", err)
+	}
+
+	if len(links) == 0 {
+		fmt.Printf("No documents link to %s\n", doc.Filepath)
+		return nil
+	}
+
+	fmt.Printf("Documents linking to %s:\n\n", doc.Filepath)
+	for _, link := range links {
+		fmt.Printf("  [%d] %s\n", link.SourceID, link.SourceFilepath)
+		fmt.Printf("       %s link %q\n", link.Kind, linkText(link.Text, link.Target))
+	}
+	fmt.Println()
+
+	return nil
+}
+
+func runOrphans(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	db := storage.NewSQLiteDB(DEFAULT_DB_PATH)
+	if err := db.Initialize(); err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	docs, err := db.OrphanDocuments(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to find orphan documents: %w", err)
+	}
+
+	if len(docs) == 0 {
+		fmt.Println("Every document is linked to from another document")
+		return nil
+	}
+
+	for _, doc := range docs {
+		fmt.Printf("[%d] %s  %s  %d bytes\n", doc.ID, doc.Filepath, doc.LastModified, doc.SizeBytes)
+	}
+
+	log.Printf("INFO: %d document(s) with no links to them\n", len(docs))
+	return nil
+}
+
+// linkText is what a link shows in the document, falling back to its
+// target for bare URLs.
+func linkText(text, target string) string {
+	if text == "" {
+		return target
+	}
+	return text
+}
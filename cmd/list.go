@@ -3,20 +3,42 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"log"
+	"os"
+	"strings"
+
 	"injestion-pipeline/storage"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	listOutput string
+	listLong   bool
+)
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all documents from database",
-	RunE:  runList,
+	Long: `Lists every stored document with its path, extension and size.
+
+--long adds the summary and keywords picked for each document at ingest. JSON
+output always carries them.`,
+	RunE: runList,
+}
+
+func init() {
+	addOutputFlag(listCmd, &listOutput)
+	listCmd.Flags().BoolVar(&listLong, "long", false, "Show each document's summary and keywords")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	if err := validateOutputFormat(listOutput); err != nil {
+		return err
+	}
+
 	db := storage.NewSQLiteDB(DEFAULT_DB_PATH)
 	if err := db.Initialize(); err != nil {
 		return fmt.Errorf("Failed to initialize database: %w", err)
@@ -28,10 +50,37 @@ func runList(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Failed to list documents: %w", err)
 	}
 
-	fmt.Printf("INFO: Total documents in database: %d\n\n", len(docs))
+	if listOutput != outputText {
+		set := resultSet{Total: len(docs), Results: []resultRecord{}}
+		for _, doc := range docs {
+			record := newDocumentRecord(doc)
+			record.Summary, record.Keywords, err = db.DocumentSummary(ctx, doc.ID)
+			if err != nil {
+				return fmt.Errorf("Failed to load summary of %s: %w", doc.Filepath, err)
+			}
+			set.Results = append(set.Results, record)
+		}
+		return writeResults(os.Stdout, listOutput, set)
+	}
+
+	log.Printf("INFO: Total documents in database: %d\n", len(docs))
 
 	for i, doc := range docs {
 		fmt.Printf("%d. %s (%s) - %d bytes\n", i+1, doc.Filepath, doc.Extension, doc.SizeBytes)
+		if !listLong {
+			continue
+		}
+
+		summary, keywords, err := db.DocumentSummary(ctx, doc.ID)
+		if err != nil {
+			return fmt.Errorf("Failed to load summary of %s: %w", doc.Filepath, err)
+		}
+		if len(keywords) > 0 {
+			fmt.Printf("   Keywords: %s\n", strings.Join(keywords, ", "))
+		}
+		if summary != "" {
+			fmt.Printf("   Summary: %s\n", summary)
+		}
 	}
 
 	return nil
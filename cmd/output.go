@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/storage"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputText     = "text"
+	outputJSON     = "json"
+	outputJSONL    = "jsonl"
+	outputCSV      = "csv"
+	outputTable    = "table"
+	outputMarkdown = "markdown"
+)
+
+var outputFormats = []string{outputText, outputJSON, outputJSONL, outputCSV, outputTable, outputMarkdown}
+
+// highlightRecord is a matched term as a byte range within its snippet text.
+type highlightRecord struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+type snippetRecord struct {
+	Text       string            `json:"text"`
+	Highlights []highlightRecord `json:"highlights"`
+}
+
+// resultRecord is the stable, machine-readable shape of a document in
+// search and list output. Fields are only ever added, never renamed.
+type resultRecord struct {
+	ID          int64               `json:"id"`
+	DriveFileID string              `json:"drive_file_id"`
+	Path        string              `json:"path"`
+	Filename    string              `json:"filename"`
+	Extension   string              `json:"extension"`
+	SizeBytes   int64               `json:"size_bytes"`
+	Modified    string              `json:"modified"`
+	Score       float64             `json:"score"`
+	Retrievers  []string            `json:"retrievers"`
+	Section     string              `json:"section"`
+	Snippet     string              `json:"snippet"`
+	Snippets    []snippetRecord     `json:"snippets"`
+	Version     int64               `json:"version,omitempty"`
+	Author      string              `json:"author,omitempty"`
+	Comments    []commentRecord     `json:"comments,omitempty"`
+	Link        string              `json:"link,omitempty"`
+	Owners      []string            `json:"owners,omitempty"`
+	Starred     bool                `json:"starred,omitempty"`
+	FrontMatter map[string][]string `json:"front_matter,omitempty"`
+	Language    string              `json:"language,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	Keywords    []string            `json:"keywords,omitempty"`
+}
+
+// commentRecord is a matching comment in comment search output.
+type commentRecord struct {
+	ID       string        `json:"id"`
+	ThreadID string        `json:"thread_id"`
+	Author   string        `json:"author"`
+	Created  string        `json:"created"`
+	Resolved bool          `json:"resolved"`
+	Anchor   string        `json:"anchor"`
+	Snippet  snippetRecord `json:"snippet"`
+}
+
+// resultSet is a page of records plus the paging details that go with it.
+type resultSet struct {
+	Query   string         `json:"query,omitempty"`
+	Total   int            `json:"total"`
+	Offset  int            `json:"offset"`
+	Results []resultRecord `json:"results"`
+}
+
+func addOutputFlag(cmd *cobra.Command, target *string) {
+	cmd.Flags().StringVarP(target, "output", "o", outputText, "Output format: "+strings.Join(outputFormats, ", "))
+}
+
+func validateOutputFormat(format string) error {
+	if !slices.Contains(outputFormats, format) {
+		return fmt.Errorf("Unknown output format %q, expected one of %s", format, strings.Join(outputFormats, ", "))
+	}
+	return nil
+}
+
+func newDocumentRecord(doc pipeline.Document) resultRecord {
+	return resultRecord{
+		ID:          doc.ID,
+		DriveFileID: doc.DriveFileID,
+		Path:        doc.Filepath,
+		Filename:    doc.Filename,
+		Extension:   doc.Extension,
+		SizeBytes:   doc.SizeBytes,
+		Modified:    doc.LastModified,
+		Retrievers:  []string{},
+		Snippets:    []snippetRecord{},
+	}
+}
+
+func newSearchRecord(result storage.SearchResult) resultRecord {
+	record := newDocumentRecord(result.Document)
+	record.Score = result.Score
+	record.Retrievers = append(record.Retrievers, result.Retrievers...)
+	if result.Section != nil {
+		record.Section = result.Section.Heading
+	}
+	if result.Metadata != nil {
+		record.Link = result.Metadata.WebViewLink
+		for _, owner := range result.Metadata.Owners {
+			record.Owners = append(record.Owners, owner.Email)
+		}
+		record.Starred = result.Metadata.Starred
+	}
+	for _, f := range result.FrontMatter {
+		if record.FrontMatter == nil {
+			record.FrontMatter = make(map[string][]string)
+		}
+		record.FrontMatter[f.Key] = append(record.FrontMatter[f.Key], f.Value)
+	}
+	record.Language = result.Language
+	record.Summary = result.Summary
+	record.Keywords = result.Keywords
+	if result.Version != nil {
+		record.Version = result.Version.Number
+		record.Author = result.Version.Author
+	}
+
+	for _, c := range result.Comments {
+		record.Comments = append(record.Comments, commentRecord{
+			ID:       c.ID,
+			ThreadID: c.ThreadID,
+			Author:   c.Author,
+			Created:  c.Created,
+			Resolved: c.Resolved,
+			Anchor:   c.Anchor,
+			Snippet:  newSnippetRecord(c.Snippet),
+		})
+	}
+
+	texts := make([]string, 0, len(result.Snippets))
+	for _, fragment := range result.Snippets {
+		record.Snippets = append(record.Snippets, newSnippetRecord(fragment))
+		texts = append(texts, fragment.Text)
+	}
+	record.Snippet = strings.Join(texts, " ")
+
+	return record
+}
+
+func newSnippetRecord(fragment storage.Fragment) snippetRecord {
+	snippet := snippetRecord{Text: fragment.Text, Highlights: []highlightRecord{}}
+	for _, h := range fragment.Highlights {
+		snippet.Highlights = append(snippet.Highlights, highlightRecord{Start: h.Start, End: h.End})
+	}
+	return snippet
+}
+
+func (s snippetRecord) fragment() storage.Fragment {
+	f := storage.Fragment{Text: s.Text}
+	for _, h := range s.Highlights {
+		f.Highlights = append(f.Highlights, storage.Highlight{Start: h.Start, End: h.End})
+	}
+	return f
+}
+
+// writeResults renders set in one of the machine-readable formats. The
+// text format is command-specific and handled by each command.
+func writeResults(w io.Writer, format string, set resultSet) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(set)
+
+	case outputJSONL:
+		enc := json.NewEncoder(w)
+		for _, record := range set.Results {
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case outputCSV:
+		return writeCSV(w, set.Results)
+
+	case outputTable:
+		return writeTable(w, set.Results)
+
+	case outputMarkdown:
+		return writeMarkdown(w, set)
+	}
+
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+func writeCSV(w io.Writer, records []resultRecord) error {
+	cw := csv.NewWriter(w)
+	header := []string{"id", "drive_file_id", "path", "filename", "extension", "size_bytes", "modified", "score", "retrievers", "section", "snippet", "link"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		row := []string{
+			strconv.FormatInt(r.ID, 10),
+			r.DriveFileID,
+			r.Path,
+			r.Filename,
+			r.Extension,
+			strconv.FormatInt(r.SizeBytes, 10),
+			r.Modified,
+			strconv.FormatFloat(r.Score, 'f', -1, 64),
+			strings.Join(r.Retrievers, "+"),
+			r.Section,
+			r.Snippet,
+			r.Link,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeTable(w io.Writer, records []resultRecord) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSCORE\tMODIFIED\tSIZE\tPATH")
+	for _, r := range records {
+		fmt.Fprintf(tw, "%d\t%.4f\t%s\t%d\t%s\n", r.ID, r.Score, r.Modified, r.SizeBytes, r.Path)
+	}
+	return tw.Flush()
+}
+
+func writeMarkdown(w io.Writer, set resultSet) error {
+	if set.Query != "" {
+		fmt.Fprintf(w, "# Results for `%s`\n\n", set.Query)
+	}
+	fmt.Fprintf(w, "%d result(s)\n\n", set.Total)
+
+	for i, r := range set.Results {
+		fmt.Fprintf(w, "## %d. %s\n\n", set.Offset+i+1, escapeMarkdown(r.Filename))
+		fmt.Fprintf(w, "- **Path:** `%s`\n", r.Path)
+		if r.Link != "" {
+			fmt.Fprintf(w, "- **Link:** <%s>\n", r.Link)
+		}
+		fmt.Fprintf(w, "- **Modified:** %s\n", r.Modified)
+		fmt.Fprintf(w, "- **Size:** %d bytes\n", r.SizeBytes)
+		if set.Query != "" {
+			fmt.Fprintf(w, "- **Score:** %.4f (%s)\n", r.Score, strings.Join(r.Retrievers, ", "))
+		}
+		if r.Section != "" {
+			fmt.Fprintf(w, "- **Section:** %s\n", escapeMarkdown(r.Section))
+		}
+		fmt.Fprintln(w)
+
+		for _, snippet := range r.Snippets {
+			fmt.Fprintf(w, "> %s\n\n", markdownMarks.render(escapeFragment(snippet.fragment())))
+		}
+	}
+
+	return nil
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "#", `\#`)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// escapeFragment escapes markdown in the fragment text, moving the
+// highlight offsets along with it.
+func escapeFragment(f storage.Fragment) storage.Fragment {
+	var b strings.Builder
+	var highlights []storage.Highlight
+	last := 0
+
+	for _, h := range f.Highlights {
+		b.WriteString(escapeMarkdown(f.Text[last:h.Start]))
+		start := b.Len()
+		b.WriteString(escapeMarkdown(f.Text[h.Start:h.End]))
+		highlights = append(highlights, storage.Highlight{Start: start, End: b.Len()})
+		last = h.End
+	}
+	b.WriteString(escapeMarkdown(f.Text[last:]))
+
+	return storage.Fragment{Text: b.String(), Highlights: highlights}
+}
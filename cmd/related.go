@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"injestion-pipeline/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	relatedLimit  int
+	relatedTerms  int
+	relatedOutput string
+)
+
+var relatedCmd = &cobra.Command{
+	Use:   "related <id|path>",
+	Short: "Find documents similar to a stored document",
+	Long: `Finds the documents most like a stored one, given by its id or path.
+
+The document's most distinctive terms (by TF-IDF over the full-text index) are
+searched for like a keyword query. Stopwords, and terms that more than half of
+the documents use, are never distinctive. When the document has vectors for the
+current --embedder, the mean of its chunk vectors is searched for as well and
+both rankings are merged with reciprocal rank fusion.
+
+Examples:
+  pipeline related 42
+  pipeline related /eng/runbooks/failover.md
+  pipeline related --embedder none --terms 10 eng/runbooks/failover.md`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRelated,
+}
+
+func init() {
+	relatedCmd.Flags().IntVarP(&relatedLimit, "limit", "l", 10, "Maximum number of results")
+	relatedCmd.Flags().IntVar(&relatedTerms, "terms", storage.DefaultRelatedTerms, "Number of distinctive terms to search for")
+	addOutputFlag(relatedCmd, &relatedOutput)
+}
+
+func runRelated(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if relatedLimit < 1 {
+		return fmt.Errorf("--limit must be at least 1")
+	}
+	if err := validateOutputFormat(relatedOutput); err != nil {
+		return err
+	}
+
+	embedder, err := newEmbedder()
+	if err != nil {
+		return err
+	}
+
+	db := storage.NewSQLiteDB(DEFAULT_DB_PATH)
+	if err := db.Initialize(); err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	source, err := db.FindDocument(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("Failed to find document: %w", err)
+	}
+
+	page, err := db.RelatedDocuments(ctx, source.ID, storage.RelatedOptions{
+		Limit:    relatedLimit,
+		Terms:    relatedTerms,
+		Embedder: embedder,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to find related documents: %w", err)
+	}
+
+	if relatedOutput != outputText {
+		set := resultSet{Query: source.Filepath, Total: len(page.Results), Results: []resultRecord{}}
+		for _, result := range page.Results {
+			set.Results = append(set.Results, newSearchRecord(result))
+		}
+		return writeResults(os.Stdout, relatedOutput, set)
+	}
+
+	terms := make([]string, 0, len(page.Terms))
+	for _, t := range page.Terms {
+		terms = append(terms, t.Term)
+	}
+	log.Printf("INFO: Documents related to %s\n", source.Filepath)
+	if len(terms) > 0 {
+		log.Printf("INFO: Distinctive terms: %s\n", strings.Join(terms, ", "))
+	}
+
+	if len(page.Results) == 0 {
+		fmt.Printf("No related documents found for %s\n", source.Filepath)
+		return nil
+	}
+
+	fmt.Printf("Found %d related document(s):\n\n", len(page.Results))
+
+	marks := terminalMarks()
+	for i, result := range page.Results {
+		printResult(i+1, result, marks)
+	}
+
+	return nil
+}
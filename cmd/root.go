@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 
+	"injestion-pipeline/embedding"
+
 	"github.com/spf13/cobra"
 )
 
@@ -11,6 +13,9 @@ var (
 	dbPath          string
 	credentialsPath string
 	tokenPath       string
+	embedderKind    string
+	embeddingURL    string
+	embeddingModel  string
 )
 
 var rootCmd = &cobra.Command{
@@ -25,11 +30,23 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&dbPath, "db", "knowledge.db", "Path to SQLite database")
 	rootCmd.PersistentFlags().StringVar(&credentialsPath, "credentials", "credentials.json", "Path to Google OAuth credentials")
 	rootCmd.PersistentFlags().StringVar(&tokenPath, "token", "token.json", "Path to OAuth token cache")
+	rootCmd.PersistentFlags().StringVar(&embedderKind, "embedder", EMBEDDER_HASH, "Embedder for semantic search: hash (offline), http or none")
+	rootCmd.PersistentFlags().StringVar(&embeddingURL, "embedding-url", embedding.DefaultHTTPBaseURL, "Base URL of an OpenAI-compatible embedding server")
+	rootCmd.PersistentFlags().StringVar(&embeddingModel, "embedding-model", "", "Model name to request from the embedding server")
 
 	rootCmd.AddCommand(ingestCmd)
 	rootCmd.AddCommand(searchCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(clearCmd)
+	rootCmd.AddCommand(indexCmd)
+	rootCmd.AddCommand(relatedCmd)
+	rootCmd.AddCommand(dupesCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(linksCmd)
+	rootCmd.AddCommand(backlinksCmd)
+	rootCmd.AddCommand(orphansCmd)
+	rootCmd.AddCommand(entitiesCmd)
 }
 
 func Execute() {
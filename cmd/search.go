@@ -2,33 +2,153 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"os"
+	"slices"
 	"strings"
+	"time"
 
+	"injestion-pipeline/acl"
+	"injestion-pipeline/embedding"
+	"injestion-pipeline/models"
+	search "injestion-pipeline/query"
 	"injestion-pipeline/storage"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	searchLimit int
+	searchLimit          int
+	searchOffset         int
+	searchPage           int
+	searchSort           string
+	searchOutput         string
+	searchSemantic       bool
+	searchMode           string
+	searchRRFK           float64
+	searchKeywordWeight  float64
+	searchSemanticWeight float64
+	searchFilenameWeight float64
+	searchPathWeight     float64
+	searchContentWeight  float64
+	searchCollapseDupes  bool
+	searchDupeThreshold  float64
+	searchAsOf           string
+	searchComments       bool
+	searchAs             string
+	searchGroups         string
 )
 
 var searchCmd = &cobra.Command{
 	Use:   "search <query>",
 	Short: "Search documents by keyword",
-	Long: `Performs full-text search across all ingested documents.
+	Long: `Performs full-text search across the filename, folder path and content
+of all ingested documents.
+
+Query syntax:
+  deploy                 word, matched in any field
+  "connection reset"     exact phrase
+  deploy*                prefix
+  deploy OR release      either term
+  -draft                 exclude documents containing a term
+  title:runbook          search one field: title (name), path (folder), body (content)
+  ext:md,txt             file extension
+  path:/eng/**           folder glob; * stays within a folder, ** crosses folders
+  modified:>2025-01-01   modified date, also >=, <, <= or a bare date
+  created:<2024          created date, compared the same way
+  size:<10kb             file size in b, kb, mb or gb
+  owner:alice@           owner whose email or name starts with the value
+  starred:true           starred, or not with starred:false
+  entity:jira=OPS-42     mentions an entity of a type: jira, email, hostname or url
+  entity:ops-42          mentions an entity of any type
+  lang:de                written in a language: en, de or uk
+  tag:runbook            a key stored in markdown front matter matches its value;
+  status:draft,review    a key also matches its plural, so tag: finds tags:
+  error:timeout          other words with a colon are searched as text
 
 Examples:
   pipeline search "login"
   pipeline search "user authentication"
-  pipeline search --limit 10 "error handling"`,
+  pipeline search --limit 10 "error handling"
+  pipeline search 'title:runbook'
+  pipeline search 'body:"connection reset" path:/eng/** -draft'
+  pipeline search 'deploy* OR release ext:md modified:>2025-01-01'
+  pipeline search 'tag:policy -status:draft'
+  pipeline search 'lang:de häuser'
+  pipeline search --filename-weight 20 "runbook"
+  pipeline search --page 2 --sort modified "deploy"
+  pipeline search --output jsonl "deploy" | jq .path
+  pipeline search --semantic "how do I roll back a release"
+  pipeline search --mode hybrid --semantic-weight 2 "revert a deployment"
+  pipeline search --collapse-dupes "quarterly report"
+  pipeline search --as-of 2025-03-31 "on-call rota"
+  pipeline search --comments "decided against"
+  pipeline search --as alice@example.com "salary bands"
+
+--mode picks the retriever: keyword (BM25, the default), semantic (cosine
+similarity between the query and chunk vectors; --semantic is shorthand)
+or hybrid, which runs both and merges them with reciprocal rank fusion.
+Semantic and hybrid search need the same --embedder settings as ingest.
+
+--collapse-dupes hides every document that is at least --dupe-threshold similar
+to the newest document of its cluster (see 'pipeline dupes'), so copies and old
+drafts do not crowd the results. With --as, the newest document that person can
+read is the one kept.
+
+--as-of answers a keyword search from the version of each document that was
+current at the end of that day (or at an exact RFC 3339 time), using the
+versions kept by ingest; 'ingest --revisions' adds Drive's revision history.
+
+--as limits results to the documents a person could open in Drive: those
+shared with them, with a group they belong to, with their domain or with anyone
+who has the link. Drive does not reveal group members, so memberships come from
+the JSON file given to --groups, which maps each group address to its members:
+  {"eng@example.com": ["alice@example.com", "sre@example.com"]}
+Documents ingested before permissions were stored are never shown with --as.
+
+--comments searches the comment threads stored by 'ingest --comments' instead
+of the documents. Each result is the document commented on, followed by the
+matching comments with their author, date and the text they were made on.
+Terms match the comment text; title: and path: terms match the document the
+comment is on.
+
+With --output json or jsonl every result carries its snippets, and each
+highlight is a byte range [start, end) into its snippet's text.
+
+Keyword search also matches other forms of the same word in documents written
+in English, German or Ukrainian, so "deploying" finds "deployed". Each document's
+language is detected at ingest and its words are reduced to their stems; the
+query's words are stemmed the same way. Documents containing the words as typed
+rank above those that only share their stems.
+
+Results are ranked by BM25. Matches in the filename are weighted
+more heavily than matches in the body by default.`,
 	RunE: runSearch,
 }
 
 func init() {
 	searchCmd.Flags().IntVarP(&searchLimit, "limit", "l", 20, "Maximum number of results")
+	searchCmd.Flags().IntVar(&searchOffset, "offset", 0, "Number of results to skip")
+	searchCmd.Flags().IntVarP(&searchPage, "page", "p", 0, "Page of results to show, counting from 1 (overrides --offset)")
+	addOutputFlag(searchCmd, &searchOutput)
+	searchCmd.Flags().StringVar(&searchMode, "mode", string(storage.ModeKeyword), "Retriever: keyword, semantic or hybrid")
+	searchCmd.Flags().BoolVar(&searchSemantic, "semantic", false, "Shorthand for --mode semantic")
+	searchCmd.Flags().Float64Var(&searchRRFK, "rrf-k", storage.DefaultFusionWeights.K, "Rank constant for hybrid fusion; larger values flatten the top ranks")
+	searchCmd.Flags().Float64Var(&searchKeywordWeight, "keyword-weight", storage.DefaultFusionWeights.Keyword, "Weight of keyword results in hybrid fusion")
+	searchCmd.Flags().Float64Var(&searchSemanticWeight, "semantic-weight", storage.DefaultFusionWeights.Semantic, "Weight of semantic results in hybrid fusion")
+	searchCmd.Flags().BoolVar(&searchCollapseDupes, "collapse-dupes", false, "Show only the newest document of each cluster of near-duplicates")
+	searchCmd.Flags().Float64Var(&searchDupeThreshold, "dupe-threshold", storage.DefaultDuplicateThreshold, "Similarity, from 0.8 to 1, at which --collapse-dupes treats documents as duplicates")
+	searchCmd.Flags().StringVar(&searchAsOf, "as-of", "", "Search the versions current at this date (YYYY-MM-DD or RFC 3339)")
+	searchCmd.Flags().StringVar(&searchAs, "as", "", "Only return documents this email address can read")
+	searchCmd.Flags().StringVar(&searchGroups, "groups", DEFAULT_GROUPS_PATH, "JSON file mapping group addresses to their members, used with --as")
+	searchCmd.Flags().BoolVar(&searchComments, "comments", false, "Search Drive comments and replies instead of document text")
+	searchCmd.Flags().StringVar(&searchSort, "sort", string(storage.SortRelevance), "Sort order: relevance, modified, path or size")
+	searchCmd.Flags().Float64Var(&searchFilenameWeight, "filename-weight", storage.DefaultFieldWeights.Filename, "BM25 weight for filename matches")
+	searchCmd.Flags().Float64Var(&searchPathWeight, "path-weight", storage.DefaultFieldWeights.Path, "BM25 weight for folder path matches")
+	searchCmd.Flags().Float64Var(&searchContentWeight, "content-weight", storage.DefaultFieldWeights.Content, "BM25 weight for content matches")
 }
 
 func runSearch(cmd *cobra.Command, args []string) error {
@@ -41,6 +161,74 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	query := strings.Join(args, " ")
 	ctx := context.Background()
 
+	if searchLimit < 1 {
+		return fmt.Errorf("--limit must be at least 1")
+	}
+
+	offset := searchOffset
+	if searchPage > 0 {
+		offset = (searchPage - 1) * searchLimit
+	}
+	if offset < 0 {
+		return fmt.Errorf("--offset cannot be negative")
+	}
+
+	if err := validateOutputFormat(searchOutput); err != nil {
+		return err
+	}
+
+	sort := storage.SortOrder(searchSort)
+	if !slices.Contains(storage.SortOrders, sort) {
+		return fmt.Errorf("Unknown sort order %q, expected one of %v", searchSort, storage.SortOrders)
+	}
+
+	mode := storage.SearchMode(searchMode)
+	if searchSemantic {
+		mode = storage.ModeSemantic
+	}
+	if !slices.Contains(storage.SearchModes, mode) {
+		return fmt.Errorf("Unknown search mode %q, expected one of %v", mode, storage.SearchModes)
+	}
+
+	var asOf time.Time
+	if searchAsOf != "" {
+		var err error
+		if asOf, err = parseAsOf(searchAsOf); err != nil {
+			return err
+		}
+		if mode != storage.ModeKeyword {
+			return fmt.Errorf("--as-of only works with --mode %s", storage.ModeKeyword)
+		}
+	}
+	if searchComments {
+		if mode != storage.ModeKeyword {
+			return fmt.Errorf("--comments only works with --mode %s", storage.ModeKeyword)
+		}
+		if searchAsOf != "" {
+			return fmt.Errorf("--comments cannot be combined with --as-of")
+		}
+	}
+
+	var principal *storage.Principal
+	if searchAs != "" {
+		var err error
+		if principal, err = loadPrincipal(searchAs, searchGroups, cmd.Flags().Changed("groups")); err != nil {
+			return err
+		}
+	}
+
+	var embedder embedding.Embedder
+	if mode != storage.ModeKeyword {
+		var err error
+		embedder, err = newEmbedder()
+		if err != nil {
+			return err
+		}
+		if embedder == nil {
+			return fmt.Errorf("--mode %s needs an embedder, not --embedder %s", mode, EMBEDDER_NONE)
+		}
+	}
+
 	db := storage.NewSQLiteDB(DEFAULT_DB_PATH)
 	if err := db.Initialize(); err != nil {
 		return fmt.Errorf("Failed to initialize database: %w", err)
@@ -49,26 +237,168 @@ func runSearch(cmd *cobra.Command, args []string) error {
 
 	log.Printf("Searching for: \"%s\"\n\n", query)
 
-	results, err := db.SearchDocuments(ctx, query, 20)
+	page, err := db.SearchDocuments(ctx, query, storage.SearchOptions{
+		Limit:    searchLimit,
+		Offset:   offset,
+		Sort:     sort,
+		Mode:     mode,
+		Embedder: embedder,
+		Weights: storage.FieldWeights{
+			Filename: searchFilenameWeight,
+			Path:     searchPathWeight,
+			Content:  searchContentWeight,
+		},
+		Fusion: storage.FusionWeights{
+			Keyword:  searchKeywordWeight,
+			Semantic: searchSemanticWeight,
+			K:        searchRRFK,
+		},
+		CollapseDuplicates: searchCollapseDupes,
+		DuplicateThreshold: searchDupeThreshold,
+		AsOf:               asOf,
+		Comments:           searchComments,
+		Principal:          principal,
+	})
 	if err != nil {
+		var parseErr *search.ParseError
+		if errors.As(err, &parseErr) {
+			return fmt.Errorf("%w\n\n%s", err, parseErr.Pointer())
+		}
 		return fmt.Errorf("Search failed: %w", err)
 	}
 
-	if len(results) == 0 {
+	if searchOutput != outputText {
+		set := resultSet{Query: query, Total: page.Total, Offset: page.Offset, Results: []resultRecord{}}
+		for _, result := range page.Results {
+			set.Results = append(set.Results, newSearchRecord(result))
+		}
+		return writeResults(os.Stdout, searchOutput, set)
+	}
+
+	if page.Total == 0 {
 		fmt.Printf("No results found for \"%s\"\n", query)
 		return nil
 	}
 
-	fmt.Printf("Found %d result(s):\n\n", len(results))
+	if len(page.Results) == 0 {
+		fmt.Printf("Found %d result(s), none at offset %d\n", page.Total, page.Offset)
+		return nil
+	}
 
-	for i, result := range results {
-		fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-		fmt.Printf("[%d] %s\n", i+1, result.Document.Filename)
-		fmt.Printf("Path: %s\n", result.Document.Filepath)
-		fmt.Printf("Modified: %s\n", result.Document.LastModified)
-		fmt.Printf("Size: %d bytes\n\n", result.Document.SizeBytes)
-		fmt.Printf("Snippet:\n%s\n\n", result.Snippet)
+	fmt.Printf("Found %d result(s), showing %d-%d:\n\n", page.Total, page.Offset+1, page.Offset+len(page.Results))
+
+	marks := terminalMarks()
+	for i, result := range page.Results {
+		printResult(page.Offset+i+1, result, marks)
 	}
 
 	return nil
 }
+
+// printResult writes one result in the text format shared by search and
+// related.
+func printResult(n int, result storage.SearchResult, marks markStyle) {
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Printf("[%d] %s\n", n, marks.render(result.Title))
+	fmt.Printf("Path: %s\n", result.Document.Filepath)
+	if result.Metadata != nil && result.Metadata.WebViewLink != "" {
+		fmt.Printf("Link: %s\n", result.Metadata.WebViewLink)
+	}
+	fmt.Printf("Modified: %s\n", result.Document.LastModified)
+	fmt.Printf("Size: %d bytes\n", result.Document.SizeBytes)
+	if result.Language != "" {
+		fmt.Printf("Language: %s\n", result.Language)
+	}
+	fmt.Printf("Score: %.4f (%s)\n", result.Score, strings.Join(result.Retrievers, ", "))
+	if result.Section != nil && result.Section.Heading != "" {
+		fmt.Printf("Section: %s\n", result.Section.Heading)
+	}
+	if len(result.FrontMatter) > 0 {
+		fmt.Printf("Front matter: %s\n", formatFrontMatter(result.FrontMatter))
+	}
+	if result.Version != nil {
+		fmt.Printf("Version: v%d", result.Version.Number)
+		if result.Version.Author != "" {
+			fmt.Printf(" by %s", result.Version.Author)
+		}
+		fmt.Println()
+	}
+	if len(result.Keywords) > 0 {
+		fmt.Printf("Keywords: %s\n", strings.Join(result.Keywords, ", "))
+	}
+	fmt.Println()
+	if result.Summary != "" {
+		fmt.Printf("Summary:\n%s\n\n", result.Summary)
+	}
+	if len(result.Snippets) > 0 {
+		fmt.Printf("Snippet:\n")
+		for _, fragment := range result.Snippets {
+			fmt.Printf("%s\n", marks.render(fragment))
+		}
+		fmt.Println()
+	}
+	for _, c := range result.Comments {
+		state := ""
+		if c.Resolved {
+			state = " (resolved)"
+		}
+		fmt.Printf("Comment by %s on %s%s:\n", c.Author, c.Created, state)
+		if c.Anchor != "" {
+			fmt.Printf("  on \"%s\"\n", c.Anchor)
+		}
+		fmt.Printf("  %s\n\n", marks.render(c.Snippet))
+	}
+}
+
+// formatFrontMatter lists front matter fields on one line, with the
+// values of a repeated key joined, as in "status: draft; tags: hr, policy".
+func formatFrontMatter(fields []models.Field) string {
+	var keys []string
+	values := make(map[string][]string)
+	for _, f := range fields {
+		if _, seen := values[f.Key]; !seen {
+			keys = append(keys, f.Key)
+		}
+		values[f.Key] = append(values[f.Key], f.Value)
+	}
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+": "+strings.Join(values[key], ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// parseAsOf reads the --as-of flag. A bare date means the end of that day,
+// so every change made on it is included.
+func parseAsOf(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Millisecond), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("Invalid --as-of %q, expected a date such as 2025-03-31 or an RFC 3339 time", value)
+}
+
+// loadPrincipal builds the principal for --as, with the groups the
+// mapping file puts them in. A missing default mapping file just means no
+// groups; one named explicitly must exist.
+func loadPrincipal(email, groupsPath string, explicit bool) (*storage.Principal, error) {
+	if !strings.Contains(email, "@") {
+		return nil, fmt.Errorf("--as needs an email address, not %q", email)
+	}
+
+	principal := &storage.Principal{Email: email}
+
+	groups, err := acl.LoadGroups(groupsPath)
+	switch {
+	case err == nil:
+		principal.Groups = groups.Memberships(email)
+	case explicit || !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("Failed to load groups: %w", err)
+	}
+
+	log.Printf("INFO: searching as %s, member of %d group(s)\n", email, len(principal.Groups))
+	return principal, nil
+}
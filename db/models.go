@@ -4,6 +4,46 @@
 
 package pipeline
 
+type Chunk struct {
+	ID         int64
+	DocumentID int64
+	Ordinal    int64
+	Heading    string
+	Content    string
+}
+
+type ChunkVector struct {
+	ChunkID int64
+	Model   string
+	Dims    int64
+	Vector  []byte
+}
+
+type ChunksFt struct {
+	Filename string
+	Path     string
+	Content  string
+}
+
+type Comment struct {
+	ID           int64
+	DocumentID   int64
+	CommentID    string
+	ThreadID     string
+	Author       string
+	Content      string
+	Anchor       string
+	CreatedTime  string
+	ModifiedTime string
+	Resolved     bool
+}
+
+type CommentsFt struct {
+	Filename string
+	Path     string
+	Content  string
+}
+
 type Document struct {
 	ID           int64
 	DriveFileID  string
@@ -15,7 +55,115 @@ type Document struct {
 	SizeBytes    int64
 }
 
+type DocumentCluster struct {
+	DocumentID int64
+	ClusterID  int64
+}
+
+type DocumentEntity struct {
+	DocumentID int64
+	Type       string
+	Value      string
+	Mentions   int64
+}
+
+type DocumentHash struct {
+	DocumentID  int64
+	Md5Checksum string
+}
+
+type DocumentKeyword struct {
+	DocumentID int64
+	Keyword    string
+	Weight     float64
+}
+
+type DocumentLanguage struct {
+	DocumentID int64
+	Language   string
+}
+
+type DocumentLink struct {
+	DocumentID        int64
+	Kind              string
+	Target            string
+	Text              string
+	TargetDriveFileID string
+	TargetPath        string
+	TargetName        string
+}
+
+type DocumentMetadatum struct {
+	DocumentID        int64
+	LastModifyingUser string
+	WebViewLink       string
+	Description       string
+	CreatedTime       string
+	Starred           bool
+	AppProperties     string
+}
+
+type DocumentOwner struct {
+	DocumentID int64
+	Email      string
+	Name       string
+}
+
+type DocumentPermission struct {
+	DocumentID int64
+	Type       string
+	Role       string
+	Email      string
+	Domain     string
+}
+
+type DocumentSignature struct {
+	DocumentID int64
+	Simhash    int64
+}
+
+type DocumentSummary struct {
+	DocumentID int64
+	Summary    string
+}
+
+type DocumentVersion struct {
+	ID           int64
+	DriveFileID  string
+	Version      int64
+	Filepath     string
+	Content      string
+	Md5Checksum  string
+	LastModified string
+	SavedAt      string
+}
+
 type DocumentsFt struct {
 	Filename string
+	Path     string
+	Content  string
+}
+
+type DocumentsStemmedFt struct {
+	Filename string
+	Path     string
+	Content  string
+}
+
+type FrontMatter struct {
+	DocumentID int64
+	Key        string
+	Value      string
+}
+
+type VersionRevision struct {
+	VersionID  int64
+	RevisionID string
+	Author     string
+}
+
+type VersionsFt struct {
+	Filename string
+	Path     string
 	Content  string
 }
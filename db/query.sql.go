@@ -9,6 +9,114 @@ import (
 	"context"
 )
 
+const closestCluster = `-- name: ClosestCluster :one
+SELECT document_clusters.cluster_id FROM document_clusters
+JOIN document_signatures ON document_signatures.document_id = document_clusters.document_id
+WHERE simhash_similarity(document_signatures.simhash, ?) >= ?
+ORDER BY simhash_similarity(document_signatures.simhash, ?) DESC, document_clusters.document_id
+LIMIT 1
+`
+
+type ClosestClusterParams struct {
+	Simhash   int64
+	Threshold float64
+}
+
+func (q *Queries) ClosestCluster(ctx context.Context, arg ClosestClusterParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, closestCluster, arg.Simhash, arg.Threshold, arg.Simhash)
+	var cluster_id int64
+	err := row.Scan(&cluster_id)
+	return cluster_id, err
+}
+
+const createChunk = `-- name: CreateChunk :one
+INSERT INTO chunks (
+  document_id, ordinal, heading, content
+) VALUES (
+  ?, ?, ?, ?
+)
+RETURNING id
+`
+
+type CreateChunkParams struct {
+	DocumentID int64
+	Ordinal    int64
+	Heading    string
+	Content    string
+}
+
+func (q *Queries) CreateChunk(ctx context.Context, arg CreateChunkParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, createChunk,
+		arg.DocumentID,
+		arg.Ordinal,
+		arg.Heading,
+		arg.Content,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const createChunkVector = `-- name: CreateChunkVector :exec
+INSERT INTO chunk_vectors (
+  chunk_id, model, dims, vector
+) VALUES (
+  ?, ?, ?, ?
+)
+`
+
+type CreateChunkVectorParams struct {
+	ChunkID int64
+	Model   string
+	Dims    int64
+	Vector  []byte
+}
+
+func (q *Queries) CreateChunkVector(ctx context.Context, arg CreateChunkVectorParams) error {
+	_, err := q.db.ExecContext(ctx, createChunkVector,
+		arg.ChunkID,
+		arg.Model,
+		arg.Dims,
+		arg.Vector,
+	)
+	return err
+}
+
+const createComment = `-- name: CreateComment :exec
+INSERT INTO comments (
+  document_id, comment_id, thread_id, author, content, anchor, created_time, modified_time, resolved
+) VALUES (
+  ?, ?, ?, ?, ?, ?, ?, ?, ?
+)
+`
+
+type CreateCommentParams struct {
+	DocumentID   int64
+	CommentID    string
+	ThreadID     string
+	Author       string
+	Content      string
+	Anchor       string
+	CreatedTime  string
+	ModifiedTime string
+	Resolved     bool
+}
+
+func (q *Queries) CreateComment(ctx context.Context, arg CreateCommentParams) error {
+	_, err := q.db.ExecContext(ctx, createComment,
+		arg.DocumentID,
+		arg.CommentID,
+		arg.ThreadID,
+		arg.Author,
+		arg.Content,
+		arg.Anchor,
+		arg.CreatedTime,
+		arg.ModifiedTime,
+		arg.Resolved,
+	)
+	return err
+}
+
 const createDocument = `-- name: CreateDocument :one
 INSERT INTO documents (
   drive_file_id, filename, filepath, content, extension, last_modified, size_bytes
@@ -52,6 +160,296 @@ func (q *Queries) CreateDocument(ctx context.Context, arg CreateDocumentParams)
 	return i, err
 }
 
+const createDocumentCluster = `-- name: CreateDocumentCluster :exec
+INSERT INTO document_clusters (
+  document_id, cluster_id
+) VALUES (
+  ?, ?
+)
+`
+
+type CreateDocumentClusterParams struct {
+	DocumentID int64
+	ClusterID  int64
+}
+
+func (q *Queries) CreateDocumentCluster(ctx context.Context, arg CreateDocumentClusterParams) error {
+	_, err := q.db.ExecContext(ctx, createDocumentCluster, arg.DocumentID, arg.ClusterID)
+	return err
+}
+
+const createDocumentEntity = `-- name: CreateDocumentEntity :exec
+INSERT INTO document_entities (
+  document_id, type, value, mentions
+) VALUES (
+  ?, ?, ?, ?
+)
+`
+
+type CreateDocumentEntityParams struct {
+	DocumentID int64
+	Type       string
+	Value      string
+	Mentions   int64
+}
+
+func (q *Queries) CreateDocumentEntity(ctx context.Context, arg CreateDocumentEntityParams) error {
+	_, err := q.db.ExecContext(ctx, createDocumentEntity,
+		arg.DocumentID,
+		arg.Type,
+		arg.Value,
+		arg.Mentions,
+	)
+	return err
+}
+
+const createDocumentHash = `-- name: CreateDocumentHash :exec
+INSERT INTO document_hashes (
+  document_id, md5_checksum
+) VALUES (
+  ?, ?
+)
+`
+
+type CreateDocumentHashParams struct {
+	DocumentID  int64
+	Md5Checksum string
+}
+
+func (q *Queries) CreateDocumentHash(ctx context.Context, arg CreateDocumentHashParams) error {
+	_, err := q.db.ExecContext(ctx, createDocumentHash, arg.DocumentID, arg.Md5Checksum)
+	return err
+}
+
+const createDocumentKeyword = `-- name: CreateDocumentKeyword :exec
+INSERT INTO document_keywords (
+  document_id, keyword, weight
+) VALUES (
+  ?, ?, ?
+)
+`
+
+type CreateDocumentKeywordParams struct {
+	DocumentID int64
+	Keyword    string
+	Weight     float64
+}
+
+func (q *Queries) CreateDocumentKeyword(ctx context.Context, arg CreateDocumentKeywordParams) error {
+	_, err := q.db.ExecContext(ctx, createDocumentKeyword, arg.DocumentID, arg.Keyword, arg.Weight)
+	return err
+}
+
+const createDocumentLanguage = `-- name: CreateDocumentLanguage :exec
+INSERT INTO document_languages (
+  document_id, language
+) VALUES (
+  ?, ?
+)
+`
+
+type CreateDocumentLanguageParams struct {
+	DocumentID int64
+	Language   string
+}
+
+func (q *Queries) CreateDocumentLanguage(ctx context.Context, arg CreateDocumentLanguageParams) error {
+	_, err := q.db.ExecContext(ctx, createDocumentLanguage, arg.DocumentID, arg.Language)
+	return err
+}
+
+const createDocumentLink = `-- name: CreateDocumentLink :exec
+INSERT INTO document_links (
+  document_id, kind, target, text, target_drive_file_id, target_path, target_name
+) VALUES (
+  ?, ?, ?, ?, ?, ?, ?
+)
+`
+
+type CreateDocumentLinkParams struct {
+	DocumentID        int64
+	Kind              string
+	Target            string
+	Text              string
+	TargetDriveFileID string
+	TargetPath        string
+	TargetName        string
+}
+
+func (q *Queries) CreateDocumentLink(ctx context.Context, arg CreateDocumentLinkParams) error {
+	_, err := q.db.ExecContext(ctx, createDocumentLink,
+		arg.DocumentID,
+		arg.Kind,
+		arg.Target,
+		arg.Text,
+		arg.TargetDriveFileID,
+		arg.TargetPath,
+		arg.TargetName,
+	)
+	return err
+}
+
+const createDocumentOwner = `-- name: CreateDocumentOwner :exec
+INSERT INTO document_owners (
+  document_id, email, name
+) VALUES (
+  ?, ?, ?
+)
+`
+
+type CreateDocumentOwnerParams struct {
+	DocumentID int64
+	Email      string
+	Name       string
+}
+
+func (q *Queries) CreateDocumentOwner(ctx context.Context, arg CreateDocumentOwnerParams) error {
+	_, err := q.db.ExecContext(ctx, createDocumentOwner, arg.DocumentID, arg.Email, arg.Name)
+	return err
+}
+
+const createDocumentPermission = `-- name: CreateDocumentPermission :exec
+INSERT INTO document_permissions (
+  document_id, type, role, email, domain
+) VALUES (
+  ?, ?, ?, ?, ?
+)
+`
+
+type CreateDocumentPermissionParams struct {
+	DocumentID int64
+	Type       string
+	Role       string
+	Email      string
+	Domain     string
+}
+
+func (q *Queries) CreateDocumentPermission(ctx context.Context, arg CreateDocumentPermissionParams) error {
+	_, err := q.db.ExecContext(ctx, createDocumentPermission,
+		arg.DocumentID,
+		arg.Type,
+		arg.Role,
+		arg.Email,
+		arg.Domain,
+	)
+	return err
+}
+
+const createDocumentSignature = `-- name: CreateDocumentSignature :exec
+INSERT INTO document_signatures (
+  document_id, simhash
+) VALUES (
+  ?, ?
+)
+`
+
+type CreateDocumentSignatureParams struct {
+	DocumentID int64
+	Simhash    int64
+}
+
+func (q *Queries) CreateDocumentSignature(ctx context.Context, arg CreateDocumentSignatureParams) error {
+	_, err := q.db.ExecContext(ctx, createDocumentSignature, arg.DocumentID, arg.Simhash)
+	return err
+}
+
+const createDocumentSummary = `-- name: CreateDocumentSummary :exec
+INSERT INTO document_summaries (
+  document_id, summary
+) VALUES (
+  ?, ?
+)
+`
+
+type CreateDocumentSummaryParams struct {
+	DocumentID int64
+	Summary    string
+}
+
+func (q *Queries) CreateDocumentSummary(ctx context.Context, arg CreateDocumentSummaryParams) error {
+	_, err := q.db.ExecContext(ctx, createDocumentSummary, arg.DocumentID, arg.Summary)
+	return err
+}
+
+const createDocumentVersion = `-- name: CreateDocumentVersion :one
+INSERT INTO document_versions (
+  drive_file_id, version, filepath, content, md5_checksum, last_modified
+) VALUES (
+  ?, ?, ?, ?, ?, ?
+)
+RETURNING id
+`
+
+type CreateDocumentVersionParams struct {
+	DriveFileID  string
+	Version      int64
+	Filepath     string
+	Content      string
+	Md5Checksum  string
+	LastModified string
+}
+
+func (q *Queries) CreateDocumentVersion(ctx context.Context, arg CreateDocumentVersionParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, createDocumentVersion,
+		arg.DriveFileID,
+		arg.Version,
+		arg.Filepath,
+		arg.Content,
+		arg.Md5Checksum,
+		arg.LastModified,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const createFrontMatterField = `-- name: CreateFrontMatterField :exec
+INSERT INTO front_matter (
+  document_id, key, value
+) VALUES (
+  ?, ?, ?
+)
+`
+
+type CreateFrontMatterFieldParams struct {
+	DocumentID int64
+	Key        string
+	Value      string
+}
+
+func (q *Queries) CreateFrontMatterField(ctx context.Context, arg CreateFrontMatterFieldParams) error {
+	_, err := q.db.ExecContext(ctx, createFrontMatterField, arg.DocumentID, arg.Key, arg.Value)
+	return err
+}
+
+const createVersionRevision = `-- name: CreateVersionRevision :exec
+INSERT INTO version_revisions (
+  version_id, revision_id, author
+) VALUES (
+  ?, ?, ?
+)
+`
+
+type CreateVersionRevisionParams struct {
+	VersionID  int64
+	RevisionID string
+	Author     string
+}
+
+func (q *Queries) CreateVersionRevision(ctx context.Context, arg CreateVersionRevisionParams) error {
+	_, err := q.db.ExecContext(ctx, createVersionRevision, arg.VersionID, arg.RevisionID, arg.Author)
+	return err
+}
+
+const deleteAllDocumentVersions = `-- name: DeleteAllDocumentVersions :exec
+DELETE FROM document_versions
+`
+
+func (q *Queries) DeleteAllDocumentVersions(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllDocumentVersions)
+	return err
+}
+
 const deleteAllDocuments = `-- name: DeleteAllDocuments :exec
 DELETE FROM documents
 `
@@ -61,6 +459,74 @@ func (q *Queries) DeleteAllDocuments(ctx context.Context) error {
 	return err
 }
 
+const deleteDocumentKeywords = `-- name: DeleteDocumentKeywords :exec
+DELETE FROM document_keywords
+WHERE document_id = ?
+`
+
+func (q *Queries) DeleteDocumentKeywords(ctx context.Context, documentID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteDocumentKeywords, documentID)
+	return err
+}
+
+const deleteDocumentOwners = `-- name: DeleteDocumentOwners :exec
+DELETE FROM document_owners
+WHERE document_id = ?
+`
+
+func (q *Queries) DeleteDocumentOwners(ctx context.Context, documentID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteDocumentOwners, documentID)
+	return err
+}
+
+const deleteDocumentPermissions = `-- name: DeleteDocumentPermissions :exec
+DELETE FROM document_permissions
+WHERE document_id = ?
+`
+
+func (q *Queries) DeleteDocumentPermissions(ctx context.Context, documentID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteDocumentPermissions, documentID)
+	return err
+}
+
+const deleteDocumentVersions = `-- name: DeleteDocumentVersions :exec
+DELETE FROM document_versions
+WHERE drive_file_id = ?
+`
+
+func (q *Queries) DeleteDocumentVersions(ctx context.Context, driveFileID string) error {
+	_, err := q.db.ExecContext(ctx, deleteDocumentVersions, driveFileID)
+	return err
+}
+
+const deleteDocumentsByDriveFileID = `-- name: DeleteDocumentsByDriveFileID :exec
+DELETE FROM documents
+WHERE drive_file_id = ?
+`
+
+func (q *Queries) DeleteDocumentsByDriveFileID(ctx context.Context, driveFileID string) error {
+	_, err := q.db.ExecContext(ctx, deleteDocumentsByDriveFileID, driveFileID)
+	return err
+}
+
+const getChunk = `-- name: GetChunk :one
+SELECT id, document_id, ordinal, heading, content FROM chunks
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetChunk(ctx context.Context, id int64) (Chunk, error) {
+	row := q.db.QueryRowContext(ctx, getChunk, id)
+	var i Chunk
+	err := row.Scan(
+		&i.ID,
+		&i.DocumentID,
+		&i.Ordinal,
+		&i.Heading,
+		&i.Content,
+	)
+	return i, err
+}
+
 const getDocument = `-- name: GetDocument :one
 SELECT id, drive_file_id, filename, filepath, content, extension, last_modified, size_bytes FROM documents
 WHERE id = ? LIMIT 1
@@ -82,59 +548,857 @@ func (q *Queries) GetDocument(ctx context.Context, id int64) (Document, error) {
 	return i, err
 }
 
-const listDocuments = `-- name: ListDocuments :many
+const getDocumentByDriveFileID = `-- name: GetDocumentByDriveFileID :one
 SELECT id, drive_file_id, filename, filepath, content, extension, last_modified, size_bytes FROM documents
-ORDER BY filename
+WHERE drive_file_id = ?
+ORDER BY id DESC LIMIT 1
 `
 
-func (q *Queries) ListDocuments(ctx context.Context) ([]Document, error) {
-	rows, err := q.db.QueryContext(ctx, listDocuments)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Document
-	for rows.Next() {
-		var i Document
-		if err := rows.Scan(
-			&i.ID,
-			&i.DriveFileID,
-			&i.Filename,
-			&i.Filepath,
-			&i.Content,
-			&i.Extension,
-			&i.LastModified,
-			&i.SizeBytes,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) GetDocumentByDriveFileID(ctx context.Context, driveFileID string) (Document, error) {
+	row := q.db.QueryRowContext(ctx, getDocumentByDriveFileID, driveFileID)
+	var i Document
+	err := row.Scan(
+		&i.ID,
+		&i.DriveFileID,
+		&i.Filename,
+		&i.Filepath,
+		&i.Content,
+		&i.Extension,
+		&i.LastModified,
+		&i.SizeBytes,
+	)
+	return i, err
 }
 
-const searchDocuments = `-- name: SearchDocuments :many
-SELECT id, drive_file_id, filename, filepath, content, extension, last_modified, size_bytes
-FROM documents
-WHERE id IN (
-    SELECT rowid FROM documents_fts WHERE documents_fts.content MATCH ?1
-)
-LIMIT ?2
+const getDocumentByPath = `-- name: GetDocumentByPath :one
+SELECT id, drive_file_id, filename, filepath, content, extension, last_modified, size_bytes FROM documents
+WHERE filepath = ?
+ORDER BY id DESC LIMIT 1
 `
 
-type SearchDocumentsParams struct {
-	Query string
-	Limit int64
+func (q *Queries) GetDocumentByPath(ctx context.Context, filepath string) (Document, error) {
+	row := q.db.QueryRowContext(ctx, getDocumentByPath, filepath)
+	var i Document
+	err := row.Scan(
+		&i.ID,
+		&i.DriveFileID,
+		&i.Filename,
+		&i.Filepath,
+		&i.Content,
+		&i.Extension,
+		&i.LastModified,
+		&i.SizeBytes,
+	)
+	return i, err
+}
+
+const getDocumentLanguage = `-- name: GetDocumentLanguage :one
+SELECT language FROM document_languages
+WHERE document_id = ?
+`
+
+func (q *Queries) GetDocumentLanguage(ctx context.Context, documentID int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, getDocumentLanguage, documentID)
+	var language string
+	err := row.Scan(&language)
+	return language, err
+}
+
+const getDocumentMetadata = `-- name: GetDocumentMetadata :one
+SELECT document_id, last_modifying_user, web_view_link, description, created_time, starred, app_properties FROM document_metadata
+WHERE document_id = ? LIMIT 1
+`
+
+func (q *Queries) GetDocumentMetadata(ctx context.Context, documentID int64) (DocumentMetadatum, error) {
+	row := q.db.QueryRowContext(ctx, getDocumentMetadata, documentID)
+	var i DocumentMetadatum
+	err := row.Scan(
+		&i.DocumentID,
+		&i.LastModifyingUser,
+		&i.WebViewLink,
+		&i.Description,
+		&i.CreatedTime,
+		&i.Starred,
+		&i.AppProperties,
+	)
+	return i, err
+}
+
+const getDocumentSummary = `-- name: GetDocumentSummary :one
+SELECT summary FROM document_summaries
+WHERE document_id = ?
+`
+
+func (q *Queries) GetDocumentSummary(ctx context.Context, documentID int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, getDocumentSummary, documentID)
+	var summary string
+	err := row.Scan(&summary)
+	return summary, err
+}
+
+const getDocumentVersion = `-- name: GetDocumentVersion :one
+SELECT id, drive_file_id, version, filepath, content, md5_checksum, last_modified, saved_at FROM document_versions
+WHERE drive_file_id = ? AND version = ? LIMIT 1
+`
+
+type GetDocumentVersionParams struct {
+	DriveFileID string
+	Version     int64
+}
+
+func (q *Queries) GetDocumentVersion(ctx context.Context, arg GetDocumentVersionParams) (DocumentVersion, error) {
+	row := q.db.QueryRowContext(ctx, getDocumentVersion, arg.DriveFileID, arg.Version)
+	var i DocumentVersion
+	err := row.Scan(
+		&i.ID,
+		&i.DriveFileID,
+		&i.Version,
+		&i.Filepath,
+		&i.Content,
+		&i.Md5Checksum,
+		&i.LastModified,
+		&i.SavedAt,
+	)
+	return i, err
+}
+
+const getLatestDocumentVersion = `-- name: GetLatestDocumentVersion :one
+SELECT id, drive_file_id, version, filepath, content, md5_checksum, last_modified, saved_at FROM document_versions
+WHERE drive_file_id = ?
+ORDER BY julianday(last_modified) DESC, version DESC LIMIT 1
+`
+
+func (q *Queries) GetLatestDocumentVersion(ctx context.Context, driveFileID string) (DocumentVersion, error) {
+	row := q.db.QueryRowContext(ctx, getLatestDocumentVersion, driveFileID)
+	var i DocumentVersion
+	err := row.Scan(
+		&i.ID,
+		&i.DriveFileID,
+		&i.Version,
+		&i.Filepath,
+		&i.Content,
+		&i.Md5Checksum,
+		&i.LastModified,
+		&i.SavedAt,
+	)
+	return i, err
+}
+
+const listBacklinks = `-- name: ListBacklinks :many
+SELECT document_links.kind, document_links.target, document_links.text,
+       sources.id AS source_id, sources.filepath AS source_filepath
+FROM documents targets
+JOIN document_links ON document_links.document_id <> targets.id AND (
+     (document_links.target_drive_file_id <> '' AND targets.drive_file_id = document_links.target_drive_file_id)
+  OR targets.filepath = document_links.target_path
+  OR (document_links.target_name <> '' AND lower(targets.filename) IN (document_links.target_name, document_links.target_name || targets.extension)))
+JOIN documents sources ON sources.id = document_links.document_id
+WHERE targets.id = ?
+ORDER BY sources.filepath, document_links.rowid
+`
+
+type ListBacklinksRow struct {
+	Kind           string
+	Target         string
+	Text           string
+	SourceID       int64
+	SourceFilepath string
+}
+
+func (q *Queries) ListBacklinks(ctx context.Context, id int64) ([]ListBacklinksRow, error) {
+	rows, err := q.db.QueryContext(ctx, listBacklinks, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListBacklinksRow
+	for rows.Next() {
+		var i ListBacklinksRow
+		if err := rows.Scan(
+			&i.Kind,
+			&i.Target,
+			&i.Text,
+			&i.SourceID,
+			&i.SourceFilepath,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChunkIDsByDriveFileID = `-- name: ListChunkIDsByDriveFileID :many
+SELECT chunks.id FROM chunks
+JOIN documents ON documents.id = chunks.document_id
+WHERE documents.drive_file_id = ?
+`
+
+func (q *Queries) ListChunkIDsByDriveFileID(ctx context.Context, driveFileID string) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listChunkIDsByDriveFileID, driveFileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listContentHashes = `-- name: ListContentHashes :many
+SELECT documents.drive_file_id, documents.filepath, document_hashes.md5_checksum
+FROM documents
+JOIN document_hashes ON document_hashes.document_id = documents.id
+`
+
+type ListContentHashesRow struct {
+	DriveFileID string
+	Filepath    string
+	Md5Checksum string
+}
+
+func (q *Queries) ListContentHashes(ctx context.Context) ([]ListContentHashesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listContentHashes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListContentHashesRow
+	for rows.Next() {
+		var i ListContentHashesRow
+		if err := rows.Scan(&i.DriveFileID, &i.Filepath, &i.Md5Checksum); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDocumentComments = `-- name: ListDocumentComments :many
+SELECT id, document_id, comment_id, thread_id, author, content, anchor, created_time, modified_time, resolved FROM comments
+WHERE document_id = ?
+ORDER BY id
+`
+
+func (q *Queries) ListDocumentComments(ctx context.Context, documentID int64) ([]Comment, error) {
+	rows, err := q.db.QueryContext(ctx, listDocumentComments, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Comment
+	for rows.Next() {
+		var i Comment
+		if err := rows.Scan(
+			&i.ID,
+			&i.DocumentID,
+			&i.CommentID,
+			&i.ThreadID,
+			&i.Author,
+			&i.Content,
+			&i.Anchor,
+			&i.CreatedTime,
+			&i.ModifiedTime,
+			&i.Resolved,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDocumentContents = `-- name: ListDocumentContents :many
+SELECT id, content FROM documents
+WHERE id > ?
+ORDER BY id
+LIMIT ?
+`
+
+type ListDocumentContentsParams struct {
+	AfterID int64
+	Limit   int64
+}
+
+type ListDocumentContentsRow struct {
+	ID      int64
+	Content string
+}
+
+func (q *Queries) ListDocumentContents(ctx context.Context, arg ListDocumentContentsParams) ([]ListDocumentContentsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDocumentContents, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDocumentContentsRow
+	for rows.Next() {
+		var i ListDocumentContentsRow
+		if err := rows.Scan(&i.ID, &i.Content); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDocumentKeywords = `-- name: ListDocumentKeywords :many
+SELECT keyword FROM document_keywords
+WHERE document_id = ?
+ORDER BY weight DESC, keyword
+`
+
+func (q *Queries) ListDocumentKeywords(ctx context.Context, documentID int64) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listDocumentKeywords, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var keyword string
+		if err := rows.Scan(&keyword); err != nil {
+			return nil, err
+		}
+		items = append(items, keyword)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDocumentLinks = `-- name: ListDocumentLinks :many
+SELECT document_links.kind, document_links.target, document_links.text,
+       COALESCE(targets.id, 0) AS target_id, COALESCE(targets.filepath, '') AS target_filepath
+FROM document_links
+LEFT JOIN documents targets ON targets.id <> document_links.document_id AND (
+     (document_links.target_drive_file_id <> '' AND targets.drive_file_id = document_links.target_drive_file_id)
+  OR targets.filepath = document_links.target_path
+  OR (document_links.target_name <> '' AND lower(targets.filename) IN (document_links.target_name, document_links.target_name || targets.extension)))
+WHERE document_links.document_id = ?
+ORDER BY document_links.rowid, targets.filepath
+`
+
+type ListDocumentLinksRow struct {
+	Kind           string
+	Target         string
+	Text           string
+	TargetID       int64
+	TargetFilepath string
+}
+
+func (q *Queries) ListDocumentLinks(ctx context.Context, documentID int64) ([]ListDocumentLinksRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDocumentLinks, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDocumentLinksRow
+	for rows.Next() {
+		var i ListDocumentLinksRow
+		if err := rows.Scan(
+			&i.Kind,
+			&i.Target,
+			&i.Text,
+			&i.TargetID,
+			&i.TargetFilepath,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDocumentOwners = `-- name: ListDocumentOwners :many
+SELECT document_id, email, name FROM document_owners
+WHERE document_id = ?
+ORDER BY rowid
+`
+
+func (q *Queries) ListDocumentOwners(ctx context.Context, documentID int64) ([]DocumentOwner, error) {
+	rows, err := q.db.QueryContext(ctx, listDocumentOwners, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DocumentOwner
+	for rows.Next() {
+		var i DocumentOwner
+		if err := rows.Scan(&i.DocumentID, &i.Email, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDocumentSignatures = `-- name: ListDocumentSignatures :many
+SELECT document_signatures.document_id, document_signatures.simhash, document_clusters.cluster_id,
+       documents.filepath, documents.last_modified, documents.size_bytes
+FROM document_signatures
+JOIN documents ON documents.id = document_signatures.document_id
+JOIN document_clusters ON document_clusters.document_id = document_signatures.document_id
+ORDER BY document_clusters.cluster_id, document_signatures.document_id
+`
+
+type ListDocumentSignaturesRow struct {
+	DocumentID   int64
+	Simhash      int64
+	ClusterID    int64
+	Filepath     string
+	LastModified string
+	SizeBytes    int64
+}
+
+func (q *Queries) ListDocumentSignatures(ctx context.Context) ([]ListDocumentSignaturesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDocumentSignatures)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDocumentSignaturesRow
+	for rows.Next() {
+		var i ListDocumentSignaturesRow
+		if err := rows.Scan(
+			&i.DocumentID,
+			&i.Simhash,
+			&i.ClusterID,
+			&i.Filepath,
+			&i.LastModified,
+			&i.SizeBytes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDocumentVectors = `-- name: ListDocumentVectors :many
+SELECT chunk_vectors.vector FROM chunk_vectors
+JOIN chunks ON chunks.id = chunk_vectors.chunk_id
+WHERE chunks.document_id = ? AND chunk_vectors.model = ?
+ORDER BY chunks.ordinal
+`
+
+type ListDocumentVectorsParams struct {
+	DocumentID int64
+	Model      string
+}
+
+func (q *Queries) ListDocumentVectors(ctx context.Context, arg ListDocumentVectorsParams) ([][]byte, error) {
+	rows, err := q.db.QueryContext(ctx, listDocumentVectors, arg.DocumentID, arg.Model)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items [][]byte
+	for rows.Next() {
+		var vector []byte
+		if err := rows.Scan(&vector); err != nil {
+			return nil, err
+		}
+		items = append(items, vector)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDocumentVersions = `-- name: ListDocumentVersions :many
+SELECT document_versions.version, document_versions.filepath, document_versions.md5_checksum,
+       document_versions.last_modified, document_versions.saved_at,
+       length(CAST(document_versions.content AS BLOB)) AS size_bytes,
+       COALESCE(version_revisions.revision_id, '') AS revision_id,
+       COALESCE(version_revisions.author, '') AS author
+FROM document_versions
+LEFT JOIN version_revisions ON version_revisions.version_id = document_versions.id
+WHERE document_versions.drive_file_id = ?
+ORDER BY julianday(document_versions.last_modified), document_versions.version
+`
+
+type ListDocumentVersionsRow struct {
+	Version      int64
+	Filepath     string
+	Md5Checksum  string
+	LastModified string
+	SavedAt      string
+	SizeBytes    int64
+	RevisionID   string
+	Author       string
+}
+
+func (q *Queries) ListDocumentVersions(ctx context.Context, driveFileID string) ([]ListDocumentVersionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDocumentVersions, driveFileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDocumentVersionsRow
+	for rows.Next() {
+		var i ListDocumentVersionsRow
+		if err := rows.Scan(
+			&i.Version,
+			&i.Filepath,
+			&i.Md5Checksum,
+			&i.LastModified,
+			&i.SavedAt,
+			&i.SizeBytes,
+			&i.RevisionID,
+			&i.Author,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDocuments = `-- name: ListDocuments :many
+SELECT id, drive_file_id, filename, filepath, content, extension, last_modified, size_bytes FROM documents
+ORDER BY filename
+`
+
+func (q *Queries) ListDocuments(ctx context.Context) ([]Document, error) {
+	rows, err := q.db.QueryContext(ctx, listDocuments)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Document
+	for rows.Next() {
+		var i Document
+		if err := rows.Scan(
+			&i.ID,
+			&i.DriveFileID,
+			&i.Filename,
+			&i.Filepath,
+			&i.Content,
+			&i.Extension,
+			&i.LastModified,
+			&i.SizeBytes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDocumentsWithoutLanguage = `-- name: ListDocumentsWithoutLanguage :many
+SELECT id, drive_file_id, filename, filepath, content, extension, last_modified, size_bytes FROM documents
+WHERE NOT EXISTS (SELECT 1 FROM document_languages WHERE document_languages.document_id = documents.id)
+ORDER BY id
+`
+
+func (q *Queries) ListDocumentsWithoutLanguage(ctx context.Context) ([]Document, error) {
+	rows, err := q.db.QueryContext(ctx, listDocumentsWithoutLanguage)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Document
+	for rows.Next() {
+		var i Document
+		if err := rows.Scan(
+			&i.ID,
+			&i.DriveFileID,
+			&i.Filename,
+			&i.Filepath,
+			&i.Content,
+			&i.Extension,
+			&i.LastModified,
+			&i.SizeBytes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDocumentsWithoutSummary = `-- name: ListDocumentsWithoutSummary :many
+SELECT id, drive_file_id, filename, filepath, content, extension, last_modified, size_bytes FROM documents
+WHERE NOT EXISTS (SELECT 1 FROM document_summaries WHERE document_summaries.document_id = documents.id)
+ORDER BY id
+`
+
+func (q *Queries) ListDocumentsWithoutSummary(ctx context.Context) ([]Document, error) {
+	rows, err := q.db.QueryContext(ctx, listDocumentsWithoutSummary)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Document
+	for rows.Next() {
+		var i Document
+		if err := rows.Scan(
+			&i.ID,
+			&i.DriveFileID,
+			&i.Filename,
+			&i.Filepath,
+			&i.Content,
+			&i.Extension,
+			&i.LastModified,
+			&i.SizeBytes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFrontMatter = `-- name: ListFrontMatter :many
+SELECT key, value FROM front_matter
+WHERE document_id = ?
+ORDER BY rowid
+`
+
+type ListFrontMatterRow struct {
+	Key   string
+	Value string
+}
+
+func (q *Queries) ListFrontMatter(ctx context.Context, documentID int64) ([]ListFrontMatterRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFrontMatter, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFrontMatterRow
+	for rows.Next() {
+		var i ListFrontMatterRow
+		if err := rows.Scan(&i.Key, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFrontMatterKeys = `-- name: ListFrontMatterKeys :many
+SELECT DISTINCT key FROM front_matter
+ORDER BY key
+`
+
+func (q *Queries) ListFrontMatterKeys(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listFrontMatterKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		items = append(items, key)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrphanDocuments = `-- name: ListOrphanDocuments :many
+SELECT id, drive_file_id, filename, filepath, content, extension, last_modified, size_bytes FROM documents targets
+WHERE NOT EXISTS (
+  SELECT 1 FROM document_links
+  WHERE document_links.document_id <> targets.id AND (
+       (document_links.target_drive_file_id <> '' AND targets.drive_file_id = document_links.target_drive_file_id)
+    OR targets.filepath = document_links.target_path
+    OR (document_links.target_name <> '' AND lower(targets.filename) IN (document_links.target_name, document_links.target_name || targets.extension)))
+)
+ORDER BY filepath
+`
+
+func (q *Queries) ListOrphanDocuments(ctx context.Context) ([]Document, error) {
+	rows, err := q.db.QueryContext(ctx, listOrphanDocuments)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Document
+	for rows.Next() {
+		var i Document
+		if err := rows.Scan(
+			&i.ID,
+			&i.DriveFileID,
+			&i.Filename,
+			&i.Filepath,
+			&i.Content,
+			&i.Extension,
+			&i.LastModified,
+			&i.SizeBytes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTopEntities = `-- name: ListTopEntities :many
+SELECT type, value, COUNT(*) AS documents, CAST(SUM(mentions) AS INTEGER) AS mentions
+FROM document_entities
+WHERE ? = '' OR type = ?
+GROUP BY type, value
+ORDER BY documents DESC, mentions DESC, type, value
+LIMIT ?
+`
+
+type ListTopEntitiesParams struct {
+	Type  string
+	Limit int64
+}
+
+type ListTopEntitiesRow struct {
+	Type      string
+	Value     string
+	Documents int64
+	Mentions  int64
+}
+
+func (q *Queries) ListTopEntities(ctx context.Context, arg ListTopEntitiesParams) ([]ListTopEntitiesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTopEntities, arg.Type, arg.Type, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTopEntitiesRow
+	for rows.Next() {
+		var i ListTopEntitiesRow
+		if err := rows.Scan(
+			&i.Type,
+			&i.Value,
+			&i.Documents,
+			&i.Mentions,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) SearchDocuments(ctx context.Context, arg SearchDocumentsParams) ([]Document, error) {
-	rows, err := q.db.QueryContext(ctx, searchDocuments, arg.Query, arg.Limit)
+const listUnchunkedDocuments = `-- name: ListUnchunkedDocuments :many
+SELECT id, drive_file_id, filename, filepath, content, extension, last_modified, size_bytes FROM documents
+WHERE NOT EXISTS (SELECT 1 FROM chunks WHERE chunks.document_id = documents.id)
+ORDER BY id
+`
+
+func (q *Queries) ListUnchunkedDocuments(ctx context.Context) ([]Document, error) {
+	rows, err := q.db.QueryContext(ctx, listUnchunkedDocuments)
 	if err != nil {
 		return nil, err
 	}
@@ -164,3 +1428,173 @@ func (q *Queries) SearchDocuments(ctx context.Context, arg SearchDocumentsParams
 	}
 	return items, nil
 }
+
+const listUnclusteredSignatures = `-- name: ListUnclusteredSignatures :many
+SELECT document_signatures.document_id, document_signatures.simhash FROM document_signatures
+LEFT JOIN document_clusters ON document_clusters.document_id = document_signatures.document_id
+WHERE document_clusters.document_id IS NULL
+ORDER BY document_signatures.document_id
+`
+
+type ListUnclusteredSignaturesRow struct {
+	DocumentID int64
+	Simhash    int64
+}
+
+func (q *Queries) ListUnclusteredSignatures(ctx context.Context) ([]ListUnclusteredSignaturesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUnclusteredSignatures)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUnclusteredSignaturesRow
+	for rows.Next() {
+		var i ListUnclusteredSignaturesRow
+		if err := rows.Scan(&i.DocumentID, &i.Simhash); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnsignedDocuments = `-- name: ListUnsignedDocuments :many
+SELECT documents.id, documents.content FROM documents
+LEFT JOIN document_signatures ON document_signatures.document_id = documents.id
+WHERE document_signatures.document_id IS NULL
+`
+
+type ListUnsignedDocumentsRow struct {
+	ID      int64
+	Content string
+}
+
+func (q *Queries) ListUnsignedDocuments(ctx context.Context) ([]ListUnsignedDocumentsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUnsignedDocuments)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUnsignedDocumentsRow
+	for rows.Next() {
+		var i ListUnsignedDocumentsRow
+		if err := rows.Scan(&i.ID, &i.Content); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVersionChecksums = `-- name: ListVersionChecksums :many
+SELECT document_versions.id, document_versions.version, document_versions.md5_checksum,
+       COALESCE(version_revisions.revision_id, '') AS revision_id
+FROM document_versions
+LEFT JOIN version_revisions ON version_revisions.version_id = document_versions.id
+WHERE document_versions.drive_file_id = ?
+`
+
+type ListVersionChecksumsRow struct {
+	ID          int64
+	Version     int64
+	Md5Checksum string
+	RevisionID  string
+}
+
+func (q *Queries) ListVersionChecksums(ctx context.Context, driveFileID string) ([]ListVersionChecksumsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listVersionChecksums, driveFileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListVersionChecksumsRow
+	for rows.Next() {
+		var i ListVersionChecksumsRow
+		if err := rows.Scan(&i.ID, &i.Version, &i.Md5Checksum, &i.RevisionID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const nextClusterID = `-- name: NextClusterID :one
+SELECT CAST(COALESCE(MAX(cluster_id), 0) + 1 AS INTEGER) FROM document_clusters
+`
+
+func (q *Queries) NextClusterID(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, nextClusterID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const pruneDocumentVersions = `-- name: PruneDocumentVersions :exec
+DELETE FROM document_versions
+WHERE document_versions.drive_file_id = ?1
+  AND document_versions.id NOT IN (
+    SELECT newest.id FROM document_versions AS newest
+    WHERE newest.drive_file_id = ?1
+    ORDER BY julianday(newest.last_modified) DESC, newest.version DESC
+    LIMIT ?2
+  )
+`
+
+type PruneDocumentVersionsParams struct {
+	DriveFileID string
+	Limit       int64
+}
+
+func (q *Queries) PruneDocumentVersions(ctx context.Context, arg PruneDocumentVersionsParams) error {
+	_, err := q.db.ExecContext(ctx, pruneDocumentVersions, arg.DriveFileID, arg.Limit)
+	return err
+}
+
+const upsertDocumentMetadata = `-- name: UpsertDocumentMetadata :exec
+INSERT OR REPLACE INTO document_metadata (
+  document_id, last_modifying_user, web_view_link, description, created_time, starred, app_properties
+) VALUES (
+  ?, ?, ?, ?, ?, ?, ?
+)
+`
+
+type UpsertDocumentMetadataParams struct {
+	DocumentID        int64
+	LastModifyingUser string
+	WebViewLink       string
+	Description       string
+	CreatedTime       string
+	Starred           bool
+	AppProperties     string
+}
+
+func (q *Queries) UpsertDocumentMetadata(ctx context.Context, arg UpsertDocumentMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, upsertDocumentMetadata,
+		arg.DocumentID,
+		arg.LastModifyingUser,
+		arg.WebViewLink,
+		arg.Description,
+		arg.CreatedTime,
+		arg.Starred,
+		arg.AppProperties,
+	)
+	return err
+}
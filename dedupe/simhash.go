@@ -0,0 +1,69 @@
+package dedupe
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// Bits is the size of a signature.
+const Bits = 64
+
+// MinWords is the fewest words a text needs to be signed. Every text
+// without words gets the same signature, and those of a few words share
+// too few features for their similarity to mean anything.
+const MinWords = 10
+
+// Signable reports whether text has enough words for its signature to be
+// compared with others.
+func Signable(text string) bool {
+	return len(words(text)) >= MinWords
+}
+
+// SimHash returns a 64-bit fingerprint of text in which similar texts get
+// fingerprints that differ in few bits. Features are the lowercased words
+// and adjacent word pairs, so both vocabulary and word order count.
+func SimHash(text string) uint64 {
+	words := words(text)
+
+	var weights [Bits]int
+	add := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for i := range weights {
+			if sum&(1<<i) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	for i, w := range words {
+		add(w)
+		if i > 0 {
+			add(words[i-1] + " " + w)
+		}
+	}
+
+	var sig uint64
+	for i, w := range weights {
+		if w > 0 {
+			sig |= 1 << i
+		}
+	}
+	return sig
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Similarity is the fraction of bits two signatures share, from 0 to 1.
+func Similarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/Bits
+}
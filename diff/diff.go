@@ -0,0 +1,200 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kind says what an Edit does to a line.
+type Kind int
+
+const (
+	Equal Kind = iota
+	Delete
+	Insert
+)
+
+// Edit is one line of an edit script turning a into b.
+type Edit struct {
+	Kind Kind
+	Line string
+}
+
+// Lines returns the shortest edit script from a to b, using Myers' O(ND)
+// algorithm.
+func Lines(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	limit := n + m
+	off := limit + 1
+	v := make([]int, 2*limit+3)
+
+	// trace keeps, for every d, the furthest x reached on each diagonal
+	// k in [-d-1, d+1] before round d ran, which is all backtracking needs.
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+
+	return nil
+}
+
+func backtrack(trace [][]int, a, b []string) []Edit {
+	var edits []Edit
+	x, y := len(a), len(b)
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, Edit{Kind: Equal, Line: a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, Edit{Kind: Insert, Line: b[y-1]})
+				y--
+			} else {
+				edits = append(edits, Edit{Kind: Delete, Line: a[x-1]})
+				x--
+			}
+		}
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// Unified renders the differences between two texts as a unified diff
+// with the given number of context lines. It returns an empty string when
+// the texts are equal.
+func Unified(a, b, fromName, toName string, context int) string {
+	edits := Lines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	for _, h := range hunks(edits, context) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", span(h.aStart, h.aCount), span(h.bStart, h.bCount))
+		for _, e := range h.edits {
+			switch e.Kind {
+			case Equal:
+				out.WriteString(" ")
+			case Delete:
+				out.WriteString("-")
+			case Insert:
+				out.WriteString("+")
+			}
+			out.WriteString(e.Line)
+			out.WriteString("\n")
+		}
+	}
+
+	return out.String()
+}
+
+type hunk struct {
+	aStart, aCount int
+	bStart, bCount int
+	edits          []Edit
+}
+
+// hunks groups changes with up to context unchanged lines around them.
+// Changes separated by at most twice the context share a hunk.
+func hunks(edits []Edit, context int) []hunk {
+	// aPos and bPos are the line indexes in a and b at which each edit applies.
+	aPos := make([]int, len(edits))
+	bPos := make([]int, len(edits))
+	var changes []int
+	aLine, bLine := 0, 0
+	for i, e := range edits {
+		aPos[i], bPos[i] = aLine, bLine
+		switch e.Kind {
+		case Equal:
+			aLine++
+			bLine++
+		case Delete:
+			aLine++
+			changes = append(changes, i)
+		case Insert:
+			bLine++
+			changes = append(changes, i)
+		}
+	}
+
+	var out []hunk
+	for c := 0; c < len(changes); {
+		first, last := changes[c], changes[c]
+		for c++; c < len(changes) && changes[c]-last-1 <= 2*context; c++ {
+			last = changes[c]
+		}
+
+		start := max(first-context, 0)
+		end := min(last+context+1, len(edits))
+
+		h := hunk{aStart: aPos[start], bStart: bPos[start], edits: edits[start:end]}
+		for _, e := range h.edits {
+			if e.Kind != Insert {
+				h.aCount++
+			}
+			if e.Kind != Delete {
+				h.bCount++
+			}
+		}
+		out = append(out, h)
+	}
+
+	return out
+}
+
+func span(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,51 @@
+package embedding
+
+import (
+	"context"
+	"math"
+)
+
+// Embedder turns text into vectors whose cosine similarity reflects how
+// close the texts are in meaning.
+type Embedder interface {
+	// Model names the vector space the embedder produces. Vectors from
+	// different models are never compared with each other.
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Normalize scales v to unit length in place. A zero vector is left as is.
+func Normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 when their
+// lengths differ or either is a zero vector.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,84 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const DefaultHashDimensions = 384
+
+const (
+	// ngramSize is the length of the character n-grams taken from each
+	// word, including the ^ and $ that mark its start and end.
+	ngramSize = 4
+	// wordWeight counts a whole-word match as this many n-gram matches.
+	wordWeight = 2
+)
+
+// HashEmbedder is an offline embedder that projects words and character
+// n-grams into a fixed number of dimensions with the hashing trick. It
+// knows nothing about synonyms, but it catches shared stems and spelling
+// variants, and it needs no model files or network.
+type HashEmbedder struct {
+	dims int
+}
+
+func NewHashEmbedder(dims int) *HashEmbedder {
+	if dims <= 0 {
+		dims = DefaultHashDimensions
+	}
+	return &HashEmbedder{dims: dims}
+}
+
+func (h *HashEmbedder) Model() string {
+	return fmt.Sprintf("hash-ngram-%d", h.dims)
+}
+
+func (h *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vectors[i] = h.embed(text)
+	}
+	return vectors, nil
+}
+
+func (h *HashEmbedder) embed(text string) []float32 {
+	counts := make(map[string]int)
+	for _, word := range words(text) {
+		counts["w:"+word] += wordWeight
+
+		padded := []rune("^" + word + "$")
+		for i := 0; i+ngramSize <= len(padded); i++ {
+			counts["n:"+string(padded[i:i+ngramSize])]++
+		}
+	}
+
+	v := make([]float32, h.dims)
+	for feature, count := range counts {
+		sum := fnv.New64a()
+		sum.Write([]byte(feature))
+		hash := sum.Sum64()
+
+		weight := float32(1 + math.Log(float64(count)))
+		if hash&(1<<63) != 0 {
+			weight = -weight
+		}
+		v[hash%uint64(h.dims)] += weight
+	}
+
+	Normalize(v)
+	return v
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
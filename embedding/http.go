@@ -0,0 +1,127 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultHTTPBaseURL = "http://localhost:8080/v1"
+	httpBatchSize      = 64
+)
+
+type HTTPConfig struct {
+	// BaseURL is the API root, for example http://localhost:11434/v1.
+	BaseURL string
+	Model   string
+	APIKey  string
+	Timeout time.Duration
+}
+
+// HTTPEmbedder calls an OpenAI-compatible /embeddings endpoint, such as
+// the ones served by llama.cpp, Ollama or vLLM.
+type HTTPEmbedder struct {
+	baseURL string
+	model   string
+	apiKey  string
+	client  *http.Client
+}
+
+func NewHTTPEmbedder(cfg HTTPConfig) (*HTTPEmbedder, error) {
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("an embedding model name is required")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultHTTPBaseURL
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+
+	return &HTTPEmbedder{
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+		model:   cfg.Model,
+		apiKey:  cfg.APIKey,
+		client:  &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (h *HTTPEmbedder) Model() string {
+	return h.model
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (h *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += httpBatchSize {
+		end := min(start+httpBatchSize, len(texts))
+		batch, err := h.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (h *HTTPEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingRequest{Model: h.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode embedding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.apiKey)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("embedding server returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var parsed embeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding server returned %d vectors for %d inputs", len(parsed.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding server returned out of range index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+
+	return vectors, nil
+}
@@ -0,0 +1,156 @@
+// Package entities finds typed entities such as ticket keys, email
+// addresses, hostnames and URLs in document text.
+package entities
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"injestion-pipeline/models"
+)
+
+// Extractor finds the entities of one type in text.
+type Extractor interface {
+	// Type names the kind of entity, such as "email". Types are compared
+	// without regard to case.
+	Type() string
+	// Extract returns the entities in content, once per mention.
+	Extract(content string) []string
+}
+
+// Pattern extracts every match of a regular expression, or of its first
+// group when it has one.
+type Pattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+	// Normalize, when set, rewrites each match; an empty result drops it.
+	Normalize func(string) string
+}
+
+func (p *Pattern) Type() string {
+	return p.Name
+}
+
+func (p *Pattern) Extract(content string) []string {
+	var found []string
+	for _, m := range p.Regexp.FindAllStringSubmatch(content, -1) {
+		value := m[0]
+		if len(m) > 1 {
+			value = m[1]
+		}
+		if p.Normalize != nil {
+			value = p.Normalize(value)
+		}
+		if value != "" {
+			found = append(found, value)
+		}
+	}
+	return found
+}
+
+// ParsePattern reads a custom extractor written as type=regexp, such as
+// "incident=INC[0-9]{6}".
+func ParsePattern(spec string) (*Pattern, error) {
+	name, expr, found := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	if !found || name == "" || expr == "" {
+		return nil, fmt.Errorf("entity pattern %q is not written as type=regexp", spec)
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("entity pattern %q: %w", name, err)
+	}
+	return &Pattern{Name: strings.ToLower(name), Regexp: re}, nil
+}
+
+// Builtin returns the extractors run on every document: Jira-style ticket
+// keys, email addresses, hostnames and URLs.
+func Builtin() []Extractor {
+	return []Extractor{
+		&Pattern{Name: "jira", Regexp: ticketKey, Normalize: normalizeTicket},
+		&Pattern{Name: "email", Regexp: email, Normalize: strings.ToLower},
+		hostnames{},
+		&Pattern{Name: "url", Regexp: url, Normalize: trimURL},
+	}
+}
+
+var (
+	ticketKey = regexp.MustCompile(`\b([A-Z][A-Z0-9]{1,9}-[1-9][0-9]{0,6})\b`)
+	email     = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	url       = regexp.MustCompile("https?://[^\\s<>()\\[\\]{}\"'`]+")
+	hostname  = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+([a-z]{2,63})\b`)
+)
+
+// notTickets are prefixes that look like ticket keys but name standards
+// and versions, as in UTF-8 or SHA-256.
+var notTickets = map[string]bool{
+	"UTF": true, "SHA": true, "ISO": true, "RFC": true, "CVE": true, "TLS": true, "MD": true, "COVID": true,
+}
+
+func normalizeTicket(key string) string {
+	project, _, _ := strings.Cut(key, "-")
+	if notTickets[project] {
+		return ""
+	}
+	return key
+}
+
+func trimURL(u string) string {
+	return strings.TrimRight(u, ".,;:!?")
+}
+
+// hostTLDs are the top-level domains a dotted name must end in to count
+// as a hostname, so file names such as notes.md and config.yaml do not.
+var hostTLDs = map[string]bool{
+	"com": true, "net": true, "org": true, "io": true, "dev": true, "app": true, "ai": true, "co": true,
+	"cloud": true, "edu": true, "gov": true, "info": true, "biz": true, "us": true, "uk": true, "de": true,
+	"fr": true, "nl": true, "eu": true, "ca": true, "au": true, "ua": true,
+	"internal": true, "local": true, "corp": true, "lan": true, "intranet": true,
+}
+
+// hostnames extracts dotted names that end in a known top-level domain,
+// leaving out the domains of email addresses.
+type hostnames struct{}
+
+func (hostnames) Type() string {
+	return "hostname"
+}
+
+func (hostnames) Extract(content string) []string {
+	var found []string
+	for _, loc := range hostname.FindAllStringSubmatchIndex(content, -1) {
+		if loc[0] > 0 && content[loc[0]-1] == '@' {
+			continue
+		}
+		if !hostTLDs[strings.ToLower(content[loc[2]:loc[3]])] {
+			continue
+		}
+		found = append(found, strings.ToLower(content[loc[0]:loc[1]]))
+	}
+	return found
+}
+
+// Extract runs every extractor over content and returns each distinct
+// entity once, with how often it occurs. Entities come in extractor order
+// and, within a type, in the order first mentioned.
+func Extract(content string, extractors []Extractor) []models.Entity {
+	var found []models.Entity
+	index := make(map[string]int)
+
+	for _, extractor := range extractors {
+		typ := strings.ToLower(extractor.Type())
+		for _, value := range extractor.Extract(content) {
+			key := typ + "\x00" + strings.ToLower(value)
+			if i, ok := index[key]; ok {
+				found[i].Mentions++
+				continue
+			}
+			index[key] = len(found)
+			found = append(found, models.Entity{Type: typ, Value: value, Mentions: 1})
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,88 @@
+// Package frontmatter splits the YAML front matter off a markdown document.
+package frontmatter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"injestion-pipeline/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Parse splits content into its front matter fields and the body that
+// follows. Front matter is a YAML mapping between a first line of "---"
+// and the next line of "---" or "...". Content without front matter is
+// returned whole with no fields.
+//
+// Keys are lowercased. A key holding a list yields a field per item, and
+// nested mappings are flattened into dotted keys such as "review.due".
+func Parse(content string) ([]models.Field, string, error) {
+	text := strings.TrimPrefix(content, "\ufeff")
+	if !strings.HasPrefix(text, "---\n") && !strings.HasPrefix(text, "---\r\n") {
+		return nil, content, nil
+	}
+
+	_, rest, _ := strings.Cut(text, "\n")
+	var header []string
+	for {
+		line, remaining, found := strings.Cut(rest, "\n")
+		trimmed := strings.TrimRight(line, "\r \t")
+		if trimmed == "---" || trimmed == "..." {
+			rest = remaining
+			break
+		}
+		if !found {
+			return nil, content, nil
+		}
+		header = append(header, line)
+		rest = remaining
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal([]byte(strings.Join(header, "\n")), &values); err != nil {
+		return nil, content, fmt.Errorf("invalid front matter: %w", err)
+	}
+
+	var fields []models.Field
+	for _, key := range sortedKeys(values) {
+		fields = flatten(fields, strings.ToLower(key), values[key])
+	}
+
+	return fields, strings.TrimLeft(rest, "\r\n"), nil
+}
+
+func flatten(fields []models.Field, key string, value any) []models.Field {
+	switch v := value.(type) {
+	case nil:
+		return fields
+	case []any:
+		for _, item := range v {
+			fields = flatten(fields, key, item)
+		}
+		return fields
+	case map[string]any:
+		for _, k := range sortedKeys(v) {
+			fields = flatten(fields, key+"."+strings.ToLower(k), v[k])
+		}
+		return fields
+	case time.Time:
+		if v.Equal(v.Truncate(24 * time.Hour)) {
+			return append(fields, models.Field{Key: key, Value: v.Format(time.DateOnly)})
+		}
+		return append(fields, models.Field{Key: key, Value: v.Format(time.RFC3339)})
+	default:
+		return append(fields, models.Field{Key: key, Value: fmt.Sprint(v)})
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return strings.ToLower(keys[i]) < strings.ToLower(keys[j]) })
+	return keys
+}
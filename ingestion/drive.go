@@ -10,13 +10,82 @@ import (
 )
 
 type DriveIngester struct {
-	service *drive.Service
+	service   *drive.Service
+	known     map[string]KnownFile
+	revisions bool
+	comments  bool
+
+	Stats IngestStats
+	// Unchanged lists the files skipped because their content had not
+	// changed, with their current metadata, which may have.
+	Unchanged []UnchangedFile
+}
+
+// UnchangedFile is a file the crawl did not download.
+type UnchangedFile struct {
+	DriveFileID string
+	Path        string
+	Metadata    *models.Metadata
+}
+
+// KnownFile is what was stored for a Drive file on an earlier run.
+type KnownFile struct {
+	Path        string
+	MD5Checksum string
+}
+
+// IngestStats counts the files a crawl downloaded and the ones it skipped
+// because they had not changed.
+type IngestStats struct {
+	Downloaded      int
+	BytesDownloaded int64
+	Skipped         int
+	BytesSaved      int64
+	Revisions       int
+	Comments        int
 }
 
 func NewDriveIngester(service *drive.Service) *DriveIngester {
 	return &DriveIngester{service: service}
 }
 
+// SkipUnchanged makes the crawl leave out files whose path and md5Checksum
+// match what known holds for their Drive id, without downloading them.
+func (d *DriveIngester) SkipUnchanged(known map[string]KnownFile) {
+	d.known = known
+}
+
+// FetchRevisions makes the crawl download the Drive revision history of
+// every file it downloads.
+func (d *DriveIngester) FetchRevisions() {
+	d.revisions = true
+}
+
+// FetchComments makes the crawl fetch the comments and replies on every
+// file it downloads.
+func (d *DriveIngester) FetchComments() {
+	d.comments = true
+}
+
+// metadata collects what Drive listed about file, fetching its permissions
+// separately when the listing left them out.
+func (d *DriveIngester) metadata(fs *FileProcessor, file *drive.File) *models.Metadata {
+	meta := FileMetadata(file)
+	if len(meta.Permissions) == 0 {
+		permissions, err := fs.ExtractPermissions(file)
+		if err != nil {
+			log.Printf("WARNING: Failed to fetch permissions of '%s': %v", file.Name, err)
+		}
+		meta.Permissions = permissions
+	}
+	return meta
+}
+
+func (d *DriveIngester) unchanged(file *drive.File, filePath string) bool {
+	prev, ok := d.known[file.Id]
+	return ok && file.Md5Checksum != "" && prev.MD5Checksum == file.Md5Checksum && prev.Path == filePath
+}
+
 func (d *DriveIngester) IngestFolder(folderId string, currentPath string) ([]*models.Document, error) {
 	log.Printf("INIT: initiating folder ingestion - %s", folderId)
 
@@ -27,7 +96,9 @@ func (d *DriveIngester) IngestFolder(folderId string, currentPath string) ([]*mo
 	for {
 		call := d.service.Files.List().
 			Q(query).
-			Fields("nextPageToken, files(id, name, mimeType, modifiedTime, size, parents)").
+			Fields("nextPageToken, files(id, name, mimeType, modifiedTime, size, md5Checksum, parents, " +
+				"owners(displayName, emailAddress), lastModifyingUser(displayName, emailAddress), webViewLink, description, createdTime, starred, appProperties, " +
+				"permissions(type, role, emailAddress, domain))").
 			PageSize(100)
 
 		if pageToken != "" {
@@ -54,9 +125,38 @@ func (d *DriveIngester) IngestFolder(folderId string, currentPath string) ([]*mo
 			if file.MimeType == MarkdownMime || file.MimeType == TextMime {
 				fs := NewFileProcessor(d.service)
 
+				if d.unchanged(file, filePath) {
+					log.Printf("INFO: unchanged, skipping download - %s\n", file.Name)
+					d.Stats.Skipped++
+					d.Stats.BytesSaved += file.Size
+					d.Unchanged = append(d.Unchanged, UnchangedFile{DriveFileID: file.Id, Path: filePath, Metadata: d.metadata(fs, file)})
+					continue
+				}
+
 				doc, err := fs.ExtractContent(file, filePath)
 				if err != nil {
 					log.Printf("WARNING: Failed to extract content from '%s': %v", file.Name, err)
+					continue
+				}
+				doc.Metadata = d.metadata(fs, file)
+
+				d.Stats.Downloaded++
+
+				if d.revisions {
+					doc.Revisions, err = fs.ExtractRevisions(file)
+					if err != nil {
+						log.Printf("WARNING: Failed to fetch revisions of '%s': %v", file.Name, err)
+					}
+					d.Stats.Revisions += len(doc.Revisions)
+				}
+				d.Stats.BytesDownloaded += fs.BytesRead
+
+				if d.comments {
+					doc.Comments, err = fs.ExtractComments(file)
+					if err != nil {
+						log.Printf("WARNING: Failed to fetch comments on '%s': %v", file.Name, err)
+					}
+					d.Stats.Comments += len(doc.Comments)
 				}
 
 				allDocuments = append(allDocuments, doc)
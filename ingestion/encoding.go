@@ -0,0 +1,149 @@
+package ingestion
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrBinary is returned for files that are labelled as text but are not.
+var ErrBinary = errors.New("content looks binary, not text")
+
+// encodingSample is how much of a file the UTF-16 and binary heuristics
+// look at.
+const encodingSample = 8192
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// decodeText turns downloaded bytes into normalized UTF-8 text and names
+// the encoding they were in. A byte order mark decides the encoding when
+// there is one; otherwise text with a NUL in every other byte is taken as
+// UTF-16, and anything that is not valid UTF-8 as Windows-1252, which
+// covers Latin-1. Line endings become "\n" and the text is put in NFC, so
+// the same words always index the same way.
+func decodeText(data []byte) (string, string, error) {
+	var text, name string
+	var err error
+
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		text, name = string(data[len(bomUTF8):]), "UTF-8"
+	case bytes.HasPrefix(data, bomUTF16LE):
+		text, err = decodeWith(unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), data)
+		name = "UTF-16LE"
+	case bytes.HasPrefix(data, bomUTF16BE):
+		text, err = decodeWith(unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM), data)
+		name = "UTF-16BE"
+	default:
+		switch utf16Order(data) {
+		case "LE":
+			text, err = decodeWith(unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), data)
+			name = "UTF-16LE"
+		case "BE":
+			text, err = decodeWith(unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), data)
+			name = "UTF-16BE"
+		default:
+			if bytes.IndexByte(data[:min(len(data), encodingSample)], 0) >= 0 {
+				return "", "", ErrBinary
+			}
+			if utf8.Valid(data) {
+				text, name = string(data), "UTF-8"
+			} else {
+				text, err = decodeWith(charmap.Windows1252, data)
+				name = "Windows-1252"
+			}
+		}
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	if looksBinary(text) {
+		return "", "", ErrBinary
+	}
+	return normalizeText(text), name, nil
+}
+
+func decodeWith(enc encoding.Encoding, data []byte) (string, error) {
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+// utf16Order guesses whether data without a byte order mark is UTF-16
+// from where its NUL bytes fall: text in Latin scripts has a NUL as the
+// high byte of nearly every character. It returns "LE", "BE" or "".
+func utf16Order(data []byte) string {
+	sample := data[:min(len(data), encodingSample)]
+	if len(sample) < 4 {
+		return ""
+	}
+
+	var even, odd int
+	for i, b := range sample {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			even++
+		} else {
+			odd++
+		}
+	}
+
+	pairs := len(sample) / 2
+	switch {
+	case odd*5 > pairs*2 && even*20 < pairs:
+		return "LE"
+	case even*5 > pairs*2 && odd*20 < pairs:
+		return "BE"
+	}
+	return ""
+}
+
+// looksBinary reports whether more than one in twenty of the first
+// characters are control characters other than whitespace.
+func looksBinary(text string) bool {
+	sample := textSample(text, encodingSample)
+	var control, total int
+	for _, r := range sample {
+		total++
+		if r == utf8.RuneError || (r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f' && r != '\v') || r == 0x7F {
+			control++
+		}
+	}
+	return total > 0 && control*20 > total
+}
+
+// textSample returns at most n bytes from the start of text, cut before a
+// character rather than through one, so that the cut is not mistaken for
+// an invalid byte.
+func textSample(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n]
+}
+
+var lineEndings = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// normalizeText turns CRLF and CR line endings into LF and composes the
+// text into Unicode NFC.
+func normalizeText(text string) string {
+	return norm.NFC.String(lineEndings.Replace(text))
+}
@@ -0,0 +1,139 @@
+package ingestion
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// encodeUTF16 encodes s as UTF-16 in the given byte order, with a byte
+// order mark when bom is set.
+func encodeUTF16(s string, bigEndian, bom bool) []byte {
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	out := make([]byte, 0, 2*len(units))
+	for _, u := range units {
+		if bigEndian {
+			out = append(out, byte(u>>8), byte(u))
+		} else {
+			out = append(out, byte(u), byte(u>>8))
+		}
+	}
+	return out
+}
+
+func TestDecodeText(t *testing.T) {
+	const sample = "Runbook: Überblick über den Failover.\nStep 1, restart the café service."
+
+	tests := []struct {
+		name     string
+		data     []byte
+		text     string
+		encoding string
+	}{
+		{"utf-8", []byte(sample), sample, "UTF-8"},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, sample...), sample, "UTF-8"},
+		{"utf-16le bom", encodeUTF16(sample, false, true), sample, "UTF-16LE"},
+		{"utf-16be bom", encodeUTF16(sample, true, true), sample, "UTF-16BE"},
+		{"utf-16le", encodeUTF16(sample, false, false), sample, "UTF-16LE"},
+		{"utf-16be", encodeUTF16(sample, true, false), sample, "UTF-16BE"},
+		{
+			name:     "windows-1252",
+			data:     []byte("caf\xe9 \x93quoted\x94 costs \x8010"),
+			text:     "café “quoted” costs €10",
+			encoding: "Windows-1252",
+		},
+		{"latin-1", []byte("Gr\xfc\xdfe aus M\xfcnchen"), "Grüße aus München", "Windows-1252"},
+		{"crlf", []byte("one\r\ntwo\rthree\n"), "one\ntwo\nthree\n", "UTF-8"},
+		{"nfc", []byte("cafe\u0301"), "caf\u00e9", "UTF-8"},
+		{"tabs and form feeds", []byte("a\tb\fc\vd"), "a\tb\fc\vd", "UTF-8"},
+		{"empty", nil, "", "UTF-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, encoding, err := decodeText(tt.data)
+			if err != nil {
+				t.Fatalf("decodeText failed: %v", err)
+			}
+			if text != tt.text {
+				t.Errorf("text = %q, want %q", text, tt.text)
+			}
+			if encoding != tt.encoding {
+				t.Errorf("encoding = %q, want %q", encoding, tt.encoding)
+			}
+		})
+	}
+}
+
+func TestDecodeTextBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x01\x00\x00\x00\x01\x00\x08\x06\x00\x00\x00")},
+		{"nul in text", []byte("mostly text but\x00 a nul byte in it")},
+		{"control characters", []byte("\x01\x02\x03\x04 header \x05\x06\x07\x08\x0e\x0f")},
+		{"utf-16 control characters", encodeUTF16("\x01\x02\x03\x04\x05\x06\x07\x08", false, true)},
+		{"gzip", []byte{0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0x4b, 0x4c, 0x4a}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, _, err := decodeText(tt.data)
+			if !errors.Is(err, ErrBinary) {
+				t.Errorf("decodeText = %q, %v, want ErrBinary", text, err)
+			}
+		})
+	}
+}
+
+func TestDecodeTextLongUTF16(t *testing.T) {
+	// The byte order is guessed from the start of the file only.
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 400)
+	for _, bigEndian := range []bool{false, true} {
+		got, _, err := decodeText(encodeUTF16(text, bigEndian, false))
+		if err != nil {
+			t.Fatalf("decodeText failed: %v", err)
+		}
+		if got != text {
+			t.Errorf("decoded %d characters, want the %d encoded", len(got), len(text))
+		}
+	}
+}
+
+func TestTextSample(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"aéb", 2, "a"},
+		{"aéb", 3, "aé"},
+		{"€€", 4, "€"},
+		{"€€", 2, ""},
+	}
+
+	for _, tt := range tests {
+		if got := textSample(tt.text, tt.n); got != tt.want {
+			t.Errorf("textSample(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestLooksBinarySampleBoundary(t *testing.T) {
+	// One control character in twenty is still text. The sample ends part
+	// way through a euro sign, which must not count as one more.
+	text := strings.Repeat(strings.Repeat("€", 19)+"\x01", 200)
+	if utf8.RuneStart(text[encodingSample]) {
+		t.Fatal("sample does not end inside a character")
+	}
+	if looksBinary(text) {
+		t.Error("looksBinary counted the character cut at the end of the sample")
+	}
+}
@@ -1,12 +1,16 @@
 package ingestion
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
 	"path/filepath"
 	"strings"
 
+	"injestion-pipeline/frontmatter"
+	"injestion-pipeline/links"
 	"injestion-pipeline/models"
 
 	"google.golang.org/api/drive/v3"
@@ -20,6 +24,10 @@ const (
 
 type FileProcessor struct {
 	service *drive.Service
+
+	// BytesRead is how many bytes of file and revision content have been
+	// downloaded, as sent by Drive rather than decoded.
+	BytesRead int64
 }
 
 func NewFileProcessor(service *drive.Service) *FileProcessor {
@@ -42,16 +50,258 @@ func (p *FileProcessor) ExtractContent(file *drive.File, fullPath string) (*mode
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
+	p.BytesRead += int64(len(contentBytes))
+
+	sum := md5.Sum(contentBytes)
+	checksum := hex.EncodeToString(sum[:])
+	if file.Md5Checksum != "" && checksum != file.Md5Checksum {
+		return nil, fmt.Errorf("checksum mismatch: downloaded %s, Drive reports %s", checksum, file.Md5Checksum)
+	}
+
+	content, encodingName, err := decodeText(contentBytes)
+	if err != nil {
+		return nil, err
+	}
+	if encodingName != "UTF-8" {
+		log.Printf("INFO: converted %s from %s\n", file.Name, encodingName)
+	}
 
 	doc := &models.Document{
 		DriveFileID:  file.Id,
 		FileName:     file.Name,
 		FilePath:     fullPath,
-		Content:      string(contentBytes),
+		Content:      content,
 		Extension:    strings.ToLower(filepath.Ext(file.Name)),
 		LastModified: file.ModifiedTime,
 		SizeBytes:    file.Size,
+		ContentHash:  checksum,
+		Metadata:     FileMetadata(file),
 	}
+	doc.FrontMatter, doc.Content = splitFrontMatter(file.Name, doc.Content)
+	doc.Links = links.Extract(doc.Content, fullPath)
 
 	return doc, nil
 }
+
+// splitFrontMatter strips the YAML front matter from a markdown file and
+// returns its fields. Front matter that does not parse is left in place
+// as part of the body.
+func splitFrontMatter(name, content string) ([]models.Field, string) {
+	if strings.ToLower(filepath.Ext(name)) != ".md" {
+		return nil, content
+	}
+
+	fields, body, err := frontmatter.Parse(content)
+	if err != nil {
+		log.Printf("WARNING: Ignoring front matter of '%s': %v", name, err)
+		return nil, content
+	}
+	return fields, body
+}
+
+// FileMetadata collects the metadata Drive listed for file.
+func FileMetadata(file *drive.File) *models.Metadata {
+	meta := &models.Metadata{
+		LastModifyingUser: driveUser(file.LastModifyingUser),
+		WebViewLink:       file.WebViewLink,
+		Description:       file.Description,
+		CreatedTime:       file.CreatedTime,
+		Starred:           file.Starred,
+		AppProperties:     file.AppProperties,
+	}
+	for _, owner := range file.Owners {
+		meta.Owners = append(meta.Owners, models.Person{Name: owner.DisplayName, Email: owner.EmailAddress})
+	}
+	meta.Permissions = filePermissions(file.Permissions)
+	return meta
+}
+
+// ExtractPermissions lists who file is shared with. Files.List only
+// includes permissions when the caller may share the file, so they are
+// fetched separately when it left them out.
+func (p *FileProcessor) ExtractPermissions(file *drive.File) ([]models.Permission, error) {
+	var listed []*drive.Permission
+	pageToken := ""
+
+	for {
+		call := p.service.Permissions.List(file.Id).
+			Fields("nextPageToken, permissions(type, role, emailAddress, domain)").
+			SupportsAllDrives(true)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+
+		response, err := call.Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list permissions: %w", err)
+		}
+		listed = append(listed, response.Permissions...)
+
+		pageToken = response.NextPageToken
+		if pageToken == "" {
+			break
+		}
+	}
+
+	return filePermissions(listed), nil
+}
+
+func filePermissions(listed []*drive.Permission) []models.Permission {
+	var permissions []models.Permission
+	for _, perm := range listed {
+		permissions = append(permissions, models.Permission{
+			Type:   perm.Type,
+			Role:   perm.Role,
+			Email:  perm.EmailAddress,
+			Domain: perm.Domain,
+		})
+	}
+	return permissions
+}
+
+// ExtractRevisions downloads the past revisions of file, oldest first. The
+// head revision is left out, since ExtractContent already fetched it.
+func (p *FileProcessor) ExtractRevisions(file *drive.File) ([]models.Revision, error) {
+	var listed []*drive.Revision
+	pageToken := ""
+
+	for {
+		call := p.service.Revisions.List(file.Id).
+			Fields("nextPageToken, revisions(id, modifiedTime, md5Checksum, lastModifyingUser(displayName, emailAddress))")
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+
+		response, err := call.Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list revisions: %w", err)
+		}
+		listed = append(listed, response.Revisions...)
+
+		pageToken = response.NextPageToken
+		if pageToken == "" {
+			break
+		}
+	}
+
+	if len(listed) > 0 {
+		listed = listed[:len(listed)-1]
+	}
+
+	revisions := make([]models.Revision, 0, len(listed))
+	for _, rev := range listed {
+		log.Printf("INFO: extracting revision %s of %s\n", rev.Id, file.Name)
+
+		response, err := p.service.Revisions.Get(file.Id, rev.Id).Download()
+		if err != nil {
+			return nil, fmt.Errorf("failed to download revision %s: %w", rev.Id, err)
+		}
+		contentBytes, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read revision %s: %w", rev.Id, err)
+		}
+		p.BytesRead += int64(len(contentBytes))
+
+		sum := md5.Sum(contentBytes)
+		checksum := hex.EncodeToString(sum[:])
+		if rev.Md5Checksum != "" && checksum != rev.Md5Checksum {
+			return nil, fmt.Errorf("checksum mismatch in revision %s: downloaded %s, Drive reports %s", rev.Id, checksum, rev.Md5Checksum)
+		}
+
+		content, _, err := decodeText(contentBytes)
+		if err != nil {
+			log.Printf("WARNING: Skipping revision %s of '%s': %v", rev.Id, file.Name, err)
+			continue
+		}
+
+		_, body := splitFrontMatter(file.Name, content)
+		revisions = append(revisions, models.Revision{
+			ID:           rev.Id,
+			ModifiedTime: rev.ModifiedTime,
+			Author:       driveUser(rev.LastModifyingUser),
+			Content:      body,
+			ContentHash:  checksum,
+		})
+	}
+
+	return revisions, nil
+}
+
+// ExtractComments fetches the comment threads on file, each comment
+// followed by its replies. Deleted comments and replies that only resolve
+// or reopen a thread are left out.
+func (p *FileProcessor) ExtractComments(file *drive.File) ([]models.Comment, error) {
+	var comments []models.Comment
+	pageToken := ""
+
+	for {
+		call := p.service.Comments.List(file.Id).
+			Fields("nextPageToken, comments(id, author(displayName, emailAddress), content, quotedFileContent(value), createdTime, modifiedTime, resolved, deleted, " +
+				"replies(id, author(displayName, emailAddress), content, createdTime, modifiedTime, deleted))").
+			PageSize(100)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+
+		response, err := call.Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list comments: %w", err)
+		}
+
+		for _, c := range response.Comments {
+			if c.Deleted {
+				continue
+			}
+
+			thread := models.Comment{
+				ID:           c.Id,
+				ThreadID:     c.Id,
+				Author:       driveUser(c.Author),
+				Content:      c.Content,
+				CreatedTime:  c.CreatedTime,
+				ModifiedTime: c.ModifiedTime,
+				Resolved:     c.Resolved,
+			}
+			if c.QuotedFileContent != nil {
+				thread.Anchor = c.QuotedFileContent.Value
+			}
+			comments = append(comments, thread)
+
+			for _, r := range c.Replies {
+				if r.Deleted || r.Content == "" {
+					continue
+				}
+				reply := thread
+				reply.ID = r.Id
+				reply.Author = driveUser(r.Author)
+				reply.Content = r.Content
+				reply.CreatedTime = r.CreatedTime
+				reply.ModifiedTime = r.ModifiedTime
+				comments = append(comments, reply)
+			}
+		}
+
+		pageToken = response.NextPageToken
+		if pageToken == "" {
+			break
+		}
+	}
+
+	if comments == nil {
+		comments = []models.Comment{}
+	}
+	return comments, nil
+}
+
+func driveUser(user *drive.User) string {
+	switch {
+	case user == nil:
+		return ""
+	case user.EmailAddress == "":
+		return user.DisplayName
+	case user.DisplayName == "":
+		return user.EmailAddress
+	}
+	return fmt.Sprintf("%s <%s>", user.DisplayName, user.EmailAddress)
+}
@@ -0,0 +1,225 @@
+package language
+
+import (
+	"sort"
+	"strings"
+)
+
+// stemEnglish is the Porter stemming algorithm. Words of one or two
+// letters and words with letters outside a-z are left alone.
+func stemEnglish(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	w := porterStep1a(word)
+	w = porterStep1b(w)
+	w = porterStep1c(w)
+	w = porterReplace(w, porterStep2Rules, 0)
+	w = porterReplace(w, porterStep3Rules, 0)
+	w = porterStep4(w)
+	w = porterStep5(w)
+	return w
+}
+
+// consonant reports whether w[i] is a consonant: not a vowel, and not a y
+// that follows a consonant.
+func consonant(w string, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !consonant(w, i-1)
+	}
+	return true
+}
+
+// measure counts the vowel-consonant sequences in w, the m of the
+// algorithm.
+func measure(w string) int {
+	m := 0
+	i := 0
+	for i < len(w) && consonant(w, i) {
+		i++
+	}
+	for i < len(w) {
+		for i < len(w) && !consonant(w, i) {
+			i++
+		}
+		if i >= len(w) {
+			break
+		}
+		m++
+		for i < len(w) && consonant(w, i) {
+			i++
+		}
+	}
+	return m
+}
+
+func hasVowel(w string) bool {
+	for i := range w {
+		if !consonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func doubleConsonant(w string) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && consonant(w, n-1)
+}
+
+// cvc reports whether w ends consonant-vowel-consonant, with the last
+// consonant not w, x or y.
+func cvc(w string) bool {
+	n := len(w)
+	if n < 3 || !consonant(w, n-1) || consonant(w, n-2) || !consonant(w, n-3) {
+		return false
+	}
+	switch w[n-1] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+func porterStep1a(w string) string {
+	switch {
+	case strings.HasSuffix(w, "sses"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ies"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ss"):
+		return w
+	case strings.HasSuffix(w, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func porterStep1b(w string) string {
+	if strings.HasSuffix(w, "eed") {
+		if measure(w[:len(w)-3]) > 0 {
+			return w[:len(w)-1]
+		}
+		return w
+	}
+
+	var stem string
+	switch {
+	case strings.HasSuffix(w, "ed") && hasVowel(w[:len(w)-2]):
+		stem = w[:len(w)-2]
+	case strings.HasSuffix(w, "ing") && hasVowel(w[:len(w)-3]):
+		stem = w[:len(w)-3]
+	default:
+		return w
+	}
+
+	switch {
+	case strings.HasSuffix(stem, "at"), strings.HasSuffix(stem, "bl"), strings.HasSuffix(stem, "iz"):
+		return stem + "e"
+	case doubleConsonant(stem):
+		switch stem[len(stem)-1] {
+		case 'l', 's', 'z':
+			return stem
+		}
+		return stem[:len(stem)-1]
+	case measure(stem) == 1 && cvc(stem):
+		return stem + "e"
+	}
+	return stem
+}
+
+func porterStep1c(w string) string {
+	if strings.HasSuffix(w, "y") && hasVowel(w[:len(w)-1]) {
+		return w[:len(w)-1] + "i"
+	}
+	return w
+}
+
+type suffixRule struct {
+	suffix, replacement string
+}
+
+// rules sorts suffix rules longest first, since the longest matching
+// suffix is the one that applies.
+func rules(pairs ...string) []suffixRule {
+	var r []suffixRule
+	for i := 0; i+1 < len(pairs); i += 2 {
+		r = append(r, suffixRule{pairs[i], pairs[i+1]})
+	}
+	sort.SliceStable(r, func(i, j int) bool { return len(r[i].suffix) > len(r[j].suffix) })
+	return r
+}
+
+var porterStep2Rules = rules(
+	"ational", "ate", "tional", "tion", "enci", "ence", "anci", "ance",
+	"izer", "ize", "bli", "ble", "alli", "al", "entli", "ent", "eli", "e",
+	"ousli", "ous", "ization", "ize", "ation", "ate", "ator", "ate",
+	"alism", "al", "iveness", "ive", "fulness", "ful", "ousness", "ous",
+	"aliti", "al", "iviti", "ive", "biliti", "ble", "logi", "log",
+)
+
+var porterStep3Rules = rules(
+	"icate", "ic", "ative", "", "alize", "al", "iciti", "ic",
+	"ical", "ic", "ful", "", "ness", "",
+)
+
+var porterStep4Rules = rules(
+	"al", "", "ance", "", "ence", "", "er", "", "ic", "", "able", "", "ible", "",
+	"ant", "", "ement", "", "ment", "", "ent", "", "ion", "", "ou", "", "ism", "",
+	"ate", "", "iti", "", "ous", "", "ive", "", "ize", "",
+)
+
+// porterReplace applies the rule for the longest suffix of w in list,
+// when the stem left has a measure above min.
+func porterReplace(w string, list []suffixRule, min int) string {
+	for _, r := range list {
+		if !strings.HasSuffix(w, r.suffix) {
+			continue
+		}
+		stem := w[:len(w)-len(r.suffix)]
+		if measure(stem) > min {
+			return stem + r.replacement
+		}
+		return w
+	}
+	return w
+}
+
+func porterStep4(w string) string {
+	for _, r := range porterStep4Rules {
+		if !strings.HasSuffix(w, r.suffix) {
+			continue
+		}
+		stem := w[:len(w)-len(r.suffix)]
+		if r.suffix == "ion" && !strings.HasSuffix(stem, "s") && !strings.HasSuffix(stem, "t") {
+			return w
+		}
+		if measure(stem) > 1 {
+			return stem
+		}
+		return w
+	}
+	return w
+}
+
+func porterStep5(w string) string {
+	if strings.HasSuffix(w, "e") {
+		stem := w[:len(w)-1]
+		if m := measure(stem); m > 1 || (m == 1 && !cvc(stem)) {
+			w = stem
+		}
+	}
+	if measure(w) > 1 && doubleConsonant(w) && strings.HasSuffix(w, "l") {
+		w = w[:len(w)-1]
+	}
+	return w
+}
@@ -0,0 +1,178 @@
+package language
+
+import "strings"
+
+// stemGerman is the Snowball German stemmer. It works on a lowercased
+// word, with ß spelled ss and the umlauts folded into their base vowels at
+// the end, so "Häuser" and "Haus" share a stem.
+func stemGerman(word string) string {
+	w := []rune(strings.ReplaceAll(word, "ß", "ss"))
+
+	// A u or y between vowels is a consonant; mark it so it is not
+	// counted as a vowel.
+	for i := 1; i+1 < len(w); i++ {
+		if !germanVowel(w[i-1]) || !germanVowel(w[i+1]) {
+			continue
+		}
+		switch w[i] {
+		case 'u':
+			w[i] = 'U'
+		case 'y':
+			w[i] = 'Y'
+		}
+	}
+
+	r1 := region(w, 0, germanVowel)
+	if r1 < 3 {
+		r1 = 3
+	}
+	r2 := region(w, r1, germanVowel)
+
+	w = germanStep1(w, r1)
+	w = germanStep2(w, r1)
+	w = germanStep3(w, r1, r2)
+
+	for i, c := range w {
+		switch c {
+		case 'U':
+			w[i] = 'u'
+		case 'Y':
+			w[i] = 'y'
+		case 'ä':
+			w[i] = 'a'
+		case 'ö':
+			w[i] = 'o'
+		case 'ü':
+			w[i] = 'u'
+		}
+	}
+	return string(w)
+}
+
+func germanVowel(r rune) bool {
+	switch r {
+	case 'a', 'e', 'i', 'o', 'u', 'y', 'ä', 'ö', 'ü':
+		return true
+	}
+	return false
+}
+
+// region returns where the Snowball region after from begins: after the
+// first non-vowel that follows a vowel. It is len(w) when there is none.
+func region(w []rune, from int, vowel func(rune) bool) int {
+	for i := from + 1; i < len(w); i++ {
+		if vowel(w[i-1]) && !vowel(w[i]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+func hasRuneSuffix(w []rune, suffix string) bool {
+	s := []rune(suffix)
+	if len(s) > len(w) {
+		return false
+	}
+	return string(w[len(w)-len(s):]) == suffix
+}
+
+// longestSuffix returns the longest of suffixes that w ends with, or "".
+func longestSuffix(w []rune, suffixes []string) string {
+	best := ""
+	for _, s := range suffixes {
+		if len([]rune(s)) > len([]rune(best)) && hasRuneSuffix(w, s) {
+			best = s
+		}
+	}
+	return best
+}
+
+func trim(w []rune, suffix string) []rune {
+	return w[:len(w)-len([]rune(suffix))]
+}
+
+// inRegion reports whether suffix, which w ends with, lies after start.
+func inRegion(w []rune, suffix string, start int) bool {
+	return len(w)-len([]rune(suffix)) >= start
+}
+
+func validSEnding(r rune) bool {
+	return strings.ContainsRune("bdfghklmnrt", r)
+}
+
+func validStEnding(r rune) bool {
+	return strings.ContainsRune("bdfghklmnt", r)
+}
+
+func germanStep1(w []rune, r1 int) []rune {
+	suffix := longestSuffix(w, []string{"em", "ern", "er", "e", "en", "es", "s"})
+	if suffix == "" || !inRegion(w, suffix, r1) {
+		return w
+	}
+
+	switch suffix {
+	case "em", "ern", "er":
+		return trim(w, suffix)
+	case "e", "en", "es":
+		w = trim(w, suffix)
+		if hasRuneSuffix(w, "niss") {
+			w = w[:len(w)-1]
+		}
+		return w
+	}
+	if len(w) >= 2 && validSEnding(w[len(w)-2]) {
+		return trim(w, suffix)
+	}
+	return w
+}
+
+func germanStep2(w []rune, r1 int) []rune {
+	suffix := longestSuffix(w, []string{"en", "er", "est", "st"})
+	if suffix == "" || !inRegion(w, suffix, r1) {
+		return w
+	}
+
+	if suffix != "st" {
+		return trim(w, suffix)
+	}
+	if len(w) >= 6 && validStEnding(w[len(w)-3]) {
+		return trim(w, suffix)
+	}
+	return w
+}
+
+func germanStep3(w []rune, r1, r2 int) []rune {
+	suffix := longestSuffix(w, []string{"end", "ung", "ig", "ik", "isch", "lich", "heit", "keit"})
+	if suffix == "" || !inRegion(w, suffix, r2) {
+		return w
+	}
+
+	switch suffix {
+	case "end", "ung":
+		w = trim(w, suffix)
+		if hasRuneSuffix(w, "ig") && !hasRuneSuffix(w, "eig") && inRegion(w, "ig", r2) {
+			w = trim(w, "ig")
+		}
+	case "ig", "ik", "isch":
+		if !hasRuneSuffix(trim(w, suffix), "e") {
+			w = trim(w, suffix)
+		}
+	case "lich", "heit":
+		w = trim(w, suffix)
+		for _, s := range []string{"er", "en"} {
+			if hasRuneSuffix(w, s) && inRegion(w, s, r1) {
+				w = trim(w, s)
+				break
+			}
+		}
+	case "keit":
+		w = trim(w, suffix)
+		for _, s := range []string{"lich", "ig"} {
+			if hasRuneSuffix(w, s) && inRegion(w, s, r2) {
+				w = trim(w, s)
+				break
+			}
+		}
+	}
+	return w
+}
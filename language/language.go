@@ -0,0 +1,153 @@
+// Package language detects whether a document is in English, German or
+// Ukrainian and reduces its words to their stems, so that a search for
+// "deploying" also finds "deployed".
+package language
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	English   = "en"
+	German    = "de"
+	Ukrainian = "uk"
+)
+
+// Languages are the languages that are detected and stemmed.
+var Languages = []string{English, German, Ukrainian}
+
+// Supported reports whether lang is one of Languages.
+func Supported(lang string) bool {
+	return stemmers[lang] != nil
+}
+
+var stemmers = map[string]func(string) string{
+	English:   stemEnglish,
+	German:    stemGerman,
+	Ukrainian: stemUkrainian,
+}
+
+// detectSample is how much of a document detection reads.
+const detectSample = 20000
+
+var stopwords = map[string]map[string]bool{
+	English: set("the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "as", "on", "are", "this", "be", "by", "not", "or", "you", "we"),
+	German:  set("der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "mit", "den", "von", "sich", "auch", "auf", "für", "im", "dem", "wir", "sie"),
+}
+
+// keywordStopwords are left out of summaries and keywords. The list is
+// longer than the ones detection counts, which are tuned to tell English
+// from German rather than to cover every common word.
+var keywordStopwords = set(
+	"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "as", "on", "are", "this", "be", "by", "not", "or", "you", "we",
+	"was", "were", "has", "have", "from", "at", "an", "can", "will", "if", "which", "all", "but", "they", "their", "there", "when", "what", "how", "our",
+	"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "mit", "den", "von", "sich", "auch", "auf", "für", "im", "dem", "wir", "sie",
+	"des", "es", "ich", "er", "sind", "wird", "werden", "oder", "aber", "wenn", "bei", "nach", "noch", "einen", "einem", "einer", "kann", "wie", "nur", "aus",
+	"і", "й", "та", "в", "у", "на", "з", "із", "до", "що", "не", "це", "як", "за", "для", "по", "від", "але", "або", "ми",
+	"ви", "він", "вона", "вони", "його", "її", "їх", "є", "так", "чи", "ще", "вже", "цей", "ця", "ці", "то", "коли", "які", "який", "яка",
+)
+
+func set(words ...string) map[string]bool {
+	m := make(map[string]bool, len(words))
+	for _, w := range words {
+		m[w] = true
+	}
+	return m
+}
+
+// Detect returns the language of text, or "" when it is none of
+// Languages or there is too little text to tell. Cyrillic text is
+// Ukrainian unless letters found only in Russian outnumber the ones found
+// only in Ukrainian; Latin text is English or German by which language's
+// common words it uses more, with umlauts and ß counting toward German.
+func Detect(text string) string {
+	if len(text) > detectSample {
+		// Cut before a character rather than through one.
+		cut := detectSample
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut]
+	}
+
+	var latin, cyrillic, ukrainian, russian, german int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			switch unicode.ToLower(r) {
+			case 'і', 'ї', 'є', 'ґ':
+				ukrainian++
+			case 'ы', 'э', 'ё', 'ъ':
+				russian++
+			}
+		case unicode.Is(unicode.Latin, r):
+			latin++
+			switch unicode.ToLower(r) {
+			case 'ä', 'ö', 'ü', 'ß':
+				german++
+			}
+		}
+	}
+
+	if cyrillic > latin {
+		if russian > ukrainian {
+			return ""
+		}
+		return Ukrainian
+	}
+
+	var english int
+	for _, word := range Words(text) {
+		if stopwords[English][word] {
+			english++
+		}
+		if stopwords[German][word] {
+			german++
+		}
+	}
+
+	switch {
+	case english == 0 && german == 0:
+		return ""
+	case german > english:
+		return German
+	}
+	return English
+}
+
+// Stem reduces a lowercased word to its stem in lang. Words in other
+// languages are returned as they are.
+func Stem(lang, word string) string {
+	stem := stemmers[lang]
+	if stem == nil {
+		return word
+	}
+	return stem(word)
+}
+
+// StemText lowercases text, splits it into words and returns their stems
+// joined by spaces, which is what the stemmed index holds.
+func StemText(lang, text string) string {
+	tokens := Words(text)
+	for i, w := range tokens {
+		tokens[i] = Stem(lang, w)
+	}
+	return strings.Join(tokens, " ")
+}
+
+// IsStopword reports whether word, lowercased, is one of the most common
+// words of any of Languages, which say little about what a text is about.
+func IsStopword(word string) bool {
+	return keywordStopwords[word]
+}
+
+// Words splits text into lowercased runs of letters and digits, as the
+// full-text tokenizer does.
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
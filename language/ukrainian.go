@@ -0,0 +1,69 @@
+package language
+
+// stemUkrainian is a light suffix-stripping stemmer for Ukrainian after
+// the Snowball Russian one: it removes a gerund ending, or a reflexive
+// ending and then an adjective, verb or noun ending, from the part of the
+// word after its first vowel. It does not try to undo vowel alternations,
+// so "ніч" and "ночі" keep different stems.
+func stemUkrainian(word string) string {
+	w := []rune(word)
+	rv := len(w)
+	for i, r := range w {
+		if ukrainianVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	if rv >= len(w) {
+		return word
+	}
+
+	if suffix := longestSuffix(w, ukrainianGerund); suffix != "" && inRegion(w, suffix, rv) {
+		w = trim(w, suffix)
+	} else {
+		if suffix := longestSuffix(w, ukrainianReflexive); suffix != "" && inRegion(w, suffix, rv) {
+			w = trim(w, suffix)
+		}
+		for _, endings := range [][]string{ukrainianAdjective, ukrainianVerb, ukrainianNoun} {
+			if suffix := longestSuffix(w, endings); suffix != "" && inRegion(w, suffix, rv) {
+				w = trim(w, suffix)
+				break
+			}
+		}
+	}
+
+	if suffix := longestSuffix(w, []string{"ість", "іст", "ь"}); suffix != "" && inRegion(w, suffix, rv) {
+		w = trim(w, suffix)
+	}
+	return string(w)
+}
+
+func ukrainianVowel(r rune) bool {
+	switch r {
+	case 'а', 'е', 'є', 'и', 'і', 'ї', 'о', 'у', 'ю', 'я':
+		return true
+	}
+	return false
+}
+
+var ukrainianGerund = []string{"вши", "вшись", "ючи", "ючись", "учи", "учись", "ячи", "ячись", "ачи", "ачись"}
+
+var ukrainianReflexive = []string{"ся", "сь", "си"}
+
+var ukrainianAdjective = []string{
+	"ий", "ій", "ого", "ому", "им", "ім", "их", "іх", "ими", "іми",
+	"ої", "ою", "ей", "ая", "яя", "оє", "еє", "ього", "ьому", "ьою",
+}
+
+var ukrainianVerb = []string{
+	"ати", "яти", "ити", "іти", "ать", "ять", "ють", "ують",
+	"ить", "іть", "ймо", "емо", "имо", "ете", "ите", "ає", "яє", "ує",
+	"ав", "ала", "ало", "али", "ив", "ила", "ило", "или", "ів", "іла", "іли",
+	"ла", "ло", "ли", "ме", "уть", "ю", "у", "є", "е",
+}
+
+var ukrainianNoun = []string{
+	"а", "я", "о", "е", "є", "и", "і", "ї", "у", "ю", "й", "ь",
+	"ам", "ям", "ом", "ем", "єм", "ах", "ях", "ами", "ями", "ою", "ею", "єю",
+	"ові", "еві", "єві", "ів", "їв", "ей", "ій", "ью",
+}
@@ -0,0 +1,124 @@
+// Package links finds the references a document makes to other documents:
+// markdown links, [[wiki links]] and Google Drive URLs.
+package links
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"injestion-pipeline/models"
+)
+
+const (
+	KindMarkdown = "markdown"
+	KindWiki     = "wiki"
+	// KindURL is a Drive URL written out in the text rather than as a link.
+	KindURL = "url"
+)
+
+var (
+	// markdownLink matches [text](target) and [text](target "title"), and
+	// images, which start with "!" and are skipped.
+	markdownLink = regexp.MustCompile(`!?\[([^\]\n]*)\]\(<?([^)\s>]+)>?(?:\s+"[^"\n]*")?\)`)
+	// referenceLink matches a reference definition such as "[1]: target".
+	referenceLink = regexp.MustCompile(`(?m)^ {0,3}\[([^\]\n]+)\]:\s+<?([^\s>]+)>?`)
+	// wikiLink matches [[Target]], [[Target#Heading]] and [[Target|text]].
+	wikiLink = regexp.MustCompile(`!?\[\[([^\]|#\n]+)(?:#[^\]|\n]*)?(?:\|([^\]\n]+))?\]\]`)
+	// driveURL matches bare links to Drive files and Docs editors.
+	driveURL = regexp.MustCompile(`https?://(?:drive|docs)\.google\.com/[^\s<>()\[\]"']+`)
+	// driveFileID finds the file id in /d/<id>/ paths and ?id=<id> queries.
+	driveFileID = regexp.MustCompile(`(?:/d/|[?&]id=)([A-Za-z0-9_-]{10,})`)
+)
+
+// Extract returns the links in content, a document stored at sourcePath,
+// each with what it can be resolved by. A target linked more than once is
+// returned once, with the text of its first link. Links within the page,
+// such as "#setup", and mailto: links are left out.
+func Extract(content, sourcePath string) []models.Link {
+	var found []models.Link
+	seen := make(map[string]bool)
+	add := func(link models.Link) {
+		if seen[link.Target] {
+			return
+		}
+		seen[link.Target] = true
+		found = append(found, link)
+	}
+
+	for _, m := range markdownLink.FindAllStringSubmatch(content, -1) {
+		if strings.HasPrefix(m[0], "!") {
+			continue
+		}
+		if link, ok := resolve(KindMarkdown, m[2], m[1], sourcePath); ok {
+			add(link)
+		}
+	}
+	for _, m := range referenceLink.FindAllStringSubmatch(content, -1) {
+		if link, ok := resolve(KindMarkdown, m[2], m[1], sourcePath); ok {
+			add(link)
+		}
+	}
+
+	for _, m := range wikiLink.FindAllStringSubmatch(content, -1) {
+		if strings.HasPrefix(m[0], "!") {
+			continue
+		}
+		target := strings.TrimSpace(m[1])
+		text := strings.TrimSpace(m[2])
+		if text == "" {
+			text = target
+		}
+		add(models.Link{
+			Kind:   KindWiki,
+			Target: target,
+			Text:   text,
+			Name:   strings.ToLower(path.Base(target)),
+		})
+	}
+
+	for _, target := range driveURL.FindAllString(content, -1) {
+		target = strings.TrimRight(target, ".,;:!?")
+		if link, ok := resolve(KindURL, target, "", sourcePath); ok {
+			add(link)
+		}
+	}
+
+	return found
+}
+
+// resolve works out what a link target points at: a Drive file id for
+// Drive URLs, nothing for other URLs, and the absolute path of a relative
+// link otherwise.
+func resolve(kind, target, text, sourcePath string) (models.Link, bool) {
+	link := models.Link{Kind: kind, Target: target, Text: strings.TrimSpace(text)}
+
+	if strings.HasPrefix(target, "#") || strings.HasPrefix(strings.ToLower(target), "mailto:") {
+		return link, false
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return link, false
+	}
+
+	if u.Scheme != "" {
+		if u.Host == "drive.google.com" || u.Host == "docs.google.com" {
+			if m := driveFileID.FindStringSubmatch(target); m != nil {
+				link.DriveFileID = m[1]
+			}
+		}
+		return link, true
+	}
+
+	if u.Path == "" {
+		return link, false
+	}
+	if strings.HasPrefix(u.Path, "/") {
+		link.Path = path.Clean(u.Path)
+	} else {
+		link.Path = path.Join(path.Dir(sourcePath), u.Path)
+	}
+	return link, true
+}
@@ -8,4 +8,138 @@ type Document struct {
 	Extension    string
 	LastModified string
 	SizeBytes    int64
+	// ContentHash is the hex MD5 of the downloaded bytes, as Drive reports
+	// it in md5Checksum.
+	ContentHash string
+	Chunks      []Chunk
+	// Revisions are past Drive revisions of the file, oldest first, when
+	// they were fetched. The head revision is the document itself.
+	Revisions []Revision
+	// Comments holds the file's comment threads when they were fetched;
+	// nil means they were not, so the stored ones are kept.
+	Comments []Comment
+	// EmbeddingModel names the model that produced the chunk vectors.
+	EmbeddingModel string
+	// Metadata is what Drive reports about the file beyond its content,
+	// when the document came from Drive.
+	Metadata *Metadata
+	// FrontMatter holds the fields of the YAML front matter that was
+	// stripped from the start of Content.
+	FrontMatter []Field
+	// Links are the references the content makes to other documents and
+	// URLs.
+	Links []Link
+	// Entities are the typed entities, such as ticket keys and email
+	// addresses, found in the content.
+	Entities []Entity
+	// Language is the code of the language the content is written in, as
+	// detected by the language package, or empty when it is not one that
+	// is stemmed.
+	Language string
+	// Summary is the few sentences that best sum up the content.
+	Summary string
+}
+
+// Field is one key and value from a document's front matter.
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Link is a reference from a document to another document or a URL.
+type Link struct {
+	// Kind is "markdown", "wiki" or "url" for a bare Drive URL.
+	Kind string
+	// Target is the destination as written in the document.
+	Target string
+	Text   string
+	// DriveFileID, Path and Name are what the target may resolve to a
+	// stored document by: the file id in a Drive URL, the absolute path a
+	// relative link points at, or the lowercased file name of a wiki link.
+	DriveFileID string
+	Path        string
+	Name        string
+}
+
+// Entity is something named in a document, such as a ticket key or an
+// email address.
+type Entity struct {
+	// Type is the lowercased name of the extractor that found it, such as
+	// "jira" or "email".
+	Type     string
+	Value    string
+	Mentions int
+}
+
+// Chunk is a section of a document that is indexed and ranked on its own.
+type Chunk struct {
+	Ordinal int
+	// Heading is the breadcrumb of headings above the chunk, such as
+	// "Runbook > Failover > DNS". It is empty for documents without headings.
+	Heading string
+	Content string
+	Vector  []float32
+}
+
+// Revision is an earlier state of a Drive file, as kept in its revision
+// history.
+type Revision struct {
+	ID           string
+	ModifiedTime string
+	// Author is the display name of whoever made the revision, with their
+	// email address when Drive shares it.
+	Author      string
+	Content     string
+	ContentHash string
+}
+
+// Comment is a comment or a reply from the discussion threads on a Drive
+// file.
+type Comment struct {
+	ID string
+	// ThreadID is the id of the comment that opened the thread, which is
+	// the comment's own id unless it is a reply.
+	ThreadID string
+	Author   string
+	Content  string
+	// Anchor is the document text the thread was made on, if any.
+	Anchor       string
+	CreatedTime  string
+	ModifiedTime string
+	// Resolved is the state of the whole thread.
+	Resolved bool
+}
+
+// Metadata is what Drive reports about a file beyond its name, size and
+// modified time.
+type Metadata struct {
+	Owners []Person
+	// LastModifyingUser is formatted like Revision.Author.
+	LastModifyingUser string
+	// WebViewLink opens the file in Drive.
+	WebViewLink   string
+	Description   string
+	CreatedTime   string
+	Starred       bool
+	AppProperties map[string]string
+	// Permissions lists who the file is shared with.
+	Permissions []Permission
+}
+
+// Permission grants a user, group, domain or anyone access to a Drive file.
+type Permission struct {
+	// Type is "user", "group", "domain" or "anyone".
+	Type string
+	// Role is "owner", "writer", "commenter", "reader" and so on; every
+	// role can read the file.
+	Role string
+	// Email is set for users and groups, Domain for domains.
+	Email  string
+	Domain string
+}
+
+// Person is a Drive user.
+type Person struct {
+	Name  string
+	Email string
 }
@@ -2,10 +2,36 @@
 SELECT * FROM documents
 WHERE id = ? LIMIT 1;
 
+-- name: GetDocumentByPath :one
+SELECT * FROM documents
+WHERE filepath = ?
+ORDER BY id DESC LIMIT 1;
+
 -- name: ListDocuments :many
 SELECT * FROM documents
 ORDER BY filename;
 
+-- name: ListUnchunkedDocuments :many
+SELECT * FROM documents
+WHERE NOT EXISTS (SELECT 1 FROM chunks WHERE chunks.document_id = documents.id)
+ORDER BY id;
+
+-- name: ListDocumentsWithoutLanguage :many
+SELECT * FROM documents
+WHERE NOT EXISTS (SELECT 1 FROM document_languages WHERE document_languages.document_id = documents.id)
+ORDER BY id;
+
+-- name: ListDocumentsWithoutSummary :many
+SELECT * FROM documents
+WHERE NOT EXISTS (SELECT 1 FROM document_summaries WHERE document_summaries.document_id = documents.id)
+ORDER BY id;
+
+-- name: ListDocumentVectors :many
+SELECT chunk_vectors.vector FROM chunk_vectors
+JOIN chunks ON chunks.id = chunk_vectors.chunk_id
+WHERE chunks.document_id = ? AND chunk_vectors.model = ?
+ORDER BY chunks.ordinal;
+
 -- name: CreateDocument :one
 INSERT INTO documents (
   drive_file_id, filename, filepath, content, extension, last_modified, size_bytes
@@ -14,13 +40,318 @@ INSERT INTO documents (
 )
 RETURNING *;
 
--- name: SearchDocuments :many
-SELECT *
+-- name: GetChunk :one
+SELECT * FROM chunks
+WHERE id = ? LIMIT 1;
+
+-- name: CreateChunk :one
+INSERT INTO chunks (
+  document_id, ordinal, heading, content
+) VALUES (
+  ?, ?, ?, ?
+)
+RETURNING id;
+
+-- name: CreateChunkVector :exec
+INSERT INTO chunk_vectors (
+  chunk_id, model, dims, vector
+) VALUES (
+  ?, ?, ?, ?
+);
+
+-- name: CreateDocumentHash :exec
+INSERT INTO document_hashes (
+  document_id, md5_checksum
+) VALUES (
+  ?, ?
+);
+
+-- name: ListContentHashes :many
+SELECT documents.drive_file_id, documents.filepath, document_hashes.md5_checksum
 FROM documents
-WHERE id IN (
-    SELECT rowid FROM documents_fts WHERE documents_fts.content MATCH sqlc.arg(query)
+JOIN document_hashes ON document_hashes.document_id = documents.id;
+
+-- name: ListChunkIDsByDriveFileID :many
+SELECT chunks.id FROM chunks
+JOIN documents ON documents.id = chunks.document_id
+WHERE documents.drive_file_id = ?;
+
+-- name: DeleteDocumentsByDriveFileID :exec
+DELETE FROM documents
+WHERE drive_file_id = ?;
+
+-- name: CreateDocumentSignature :exec
+INSERT INTO document_signatures (
+  document_id, simhash
+) VALUES (
+  ?, ?
+);
+
+-- name: ListDocumentSignatures :many
+SELECT document_signatures.document_id, document_signatures.simhash, document_clusters.cluster_id,
+       documents.filepath, documents.last_modified, documents.size_bytes
+FROM document_signatures
+JOIN documents ON documents.id = document_signatures.document_id
+JOIN document_clusters ON document_clusters.document_id = document_signatures.document_id
+ORDER BY document_clusters.cluster_id, document_signatures.document_id;
+
+-- name: ListUnclusteredSignatures :many
+SELECT document_signatures.document_id, document_signatures.simhash FROM document_signatures
+LEFT JOIN document_clusters ON document_clusters.document_id = document_signatures.document_id
+WHERE document_clusters.document_id IS NULL
+ORDER BY document_signatures.document_id;
+
+-- name: CreateDocumentCluster :exec
+INSERT INTO document_clusters (
+  document_id, cluster_id
+) VALUES (
+  ?, ?
+);
+
+-- name: ClosestCluster :one
+SELECT document_clusters.cluster_id FROM document_clusters
+JOIN document_signatures ON document_signatures.document_id = document_clusters.document_id
+WHERE simhash_similarity(document_signatures.simhash, sqlc.arg(simhash)) >= sqlc.arg(threshold)
+ORDER BY simhash_similarity(document_signatures.simhash, sqlc.arg(simhash)) DESC, document_clusters.document_id
+LIMIT 1;
+
+-- name: NextClusterID :one
+SELECT CAST(COALESCE(MAX(cluster_id), 0) + 1 AS INTEGER) FROM document_clusters;
+
+-- name: ListUnsignedDocuments :many
+SELECT documents.id, documents.content FROM documents
+LEFT JOIN document_signatures ON document_signatures.document_id = documents.id
+WHERE document_signatures.document_id IS NULL;
+
+-- name: GetDocumentByDriveFileID :one
+SELECT * FROM documents
+WHERE drive_file_id = ?
+ORDER BY id DESC LIMIT 1;
+
+-- name: CreateDocumentVersion :one
+INSERT INTO document_versions (
+  drive_file_id, version, filepath, content, md5_checksum, last_modified
+) VALUES (
+  ?, ?, ?, ?, ?, ?
+)
+RETURNING id;
+
+-- name: GetLatestDocumentVersion :one
+SELECT * FROM document_versions
+WHERE drive_file_id = ?
+ORDER BY julianday(last_modified) DESC, version DESC LIMIT 1;
+
+-- name: GetDocumentVersion :one
+SELECT * FROM document_versions
+WHERE drive_file_id = ? AND version = ? LIMIT 1;
+
+-- name: ListDocumentVersions :many
+SELECT document_versions.version, document_versions.filepath, document_versions.md5_checksum,
+       document_versions.last_modified, document_versions.saved_at,
+       length(CAST(document_versions.content AS BLOB)) AS size_bytes,
+       COALESCE(version_revisions.revision_id, '') AS revision_id,
+       COALESCE(version_revisions.author, '') AS author
+FROM document_versions
+LEFT JOIN version_revisions ON version_revisions.version_id = document_versions.id
+WHERE document_versions.drive_file_id = ?
+ORDER BY julianday(document_versions.last_modified), document_versions.version;
+
+-- name: PruneDocumentVersions :exec
+DELETE FROM document_versions
+WHERE document_versions.drive_file_id = ?1
+  AND document_versions.id NOT IN (
+    SELECT newest.id FROM document_versions AS newest
+    WHERE newest.drive_file_id = ?1
+    ORDER BY julianday(newest.last_modified) DESC, newest.version DESC
+    LIMIT ?2
+  );
+
+-- name: ListVersionChecksums :many
+SELECT document_versions.id, document_versions.version, document_versions.md5_checksum,
+       COALESCE(version_revisions.revision_id, '') AS revision_id
+FROM document_versions
+LEFT JOIN version_revisions ON version_revisions.version_id = document_versions.id
+WHERE document_versions.drive_file_id = ?;
+
+-- name: CreateVersionRevision :exec
+INSERT INTO version_revisions (
+  version_id, revision_id, author
+) VALUES (
+  ?, ?, ?
+);
+
+-- name: CreateComment :exec
+INSERT INTO comments (
+  document_id, comment_id, thread_id, author, content, anchor, created_time, modified_time, resolved
+) VALUES (
+  ?, ?, ?, ?, ?, ?, ?, ?, ?
+);
+
+-- name: ListDocumentComments :many
+SELECT * FROM comments
+WHERE document_id = ?
+ORDER BY id;
+
+-- name: UpsertDocumentMetadata :exec
+INSERT OR REPLACE INTO document_metadata (
+  document_id, last_modifying_user, web_view_link, description, created_time, starred, app_properties
+) VALUES (
+  ?, ?, ?, ?, ?, ?, ?
+);
+
+-- name: GetDocumentMetadata :one
+SELECT * FROM document_metadata
+WHERE document_id = ? LIMIT 1;
+
+-- name: CreateDocumentOwner :exec
+INSERT INTO document_owners (
+  document_id, email, name
+) VALUES (
+  ?, ?, ?
+);
+
+-- name: ListDocumentOwners :many
+SELECT * FROM document_owners
+WHERE document_id = ?
+ORDER BY rowid;
+
+-- name: DeleteDocumentOwners :exec
+DELETE FROM document_owners
+WHERE document_id = ?;
+
+-- name: CreateDocumentPermission :exec
+INSERT INTO document_permissions (
+  document_id, type, role, email, domain
+) VALUES (
+  ?, ?, ?, ?, ?
+);
+
+-- name: DeleteDocumentPermissions :exec
+DELETE FROM document_permissions
+WHERE document_id = ?;
+
+-- name: CreateFrontMatterField :exec
+INSERT INTO front_matter (
+  document_id, key, value
+) VALUES (
+  ?, ?, ?
+);
+
+-- name: ListFrontMatter :many
+SELECT key, value FROM front_matter
+WHERE document_id = ?
+ORDER BY rowid;
+
+-- name: ListFrontMatterKeys :many
+SELECT DISTINCT key FROM front_matter
+ORDER BY key;
+
+-- name: CreateDocumentLink :exec
+INSERT INTO document_links (
+  document_id, kind, target, text, target_drive_file_id, target_path, target_name
+) VALUES (
+  ?, ?, ?, ?, ?, ?, ?
+);
+
+-- name: ListDocumentLinks :many
+SELECT document_links.kind, document_links.target, document_links.text,
+       COALESCE(targets.id, 0) AS target_id, COALESCE(targets.filepath, '') AS target_filepath
+FROM document_links
+LEFT JOIN documents targets ON targets.id <> document_links.document_id AND (
+     (document_links.target_drive_file_id <> '' AND targets.drive_file_id = document_links.target_drive_file_id)
+  OR targets.filepath = document_links.target_path
+  OR (document_links.target_name <> '' AND lower(targets.filename) IN (document_links.target_name, document_links.target_name || targets.extension)))
+WHERE document_links.document_id = ?
+ORDER BY document_links.rowid, targets.filepath;
+
+-- name: ListBacklinks :many
+SELECT document_links.kind, document_links.target, document_links.text,
+       sources.id AS source_id, sources.filepath AS source_filepath
+FROM documents targets
+JOIN document_links ON document_links.document_id <> targets.id AND (
+     (document_links.target_drive_file_id <> '' AND targets.drive_file_id = document_links.target_drive_file_id)
+  OR targets.filepath = document_links.target_path
+  OR (document_links.target_name <> '' AND lower(targets.filename) IN (document_links.target_name, document_links.target_name || targets.extension)))
+JOIN documents sources ON sources.id = document_links.document_id
+WHERE targets.id = ?
+ORDER BY sources.filepath, document_links.rowid;
+
+-- name: ListOrphanDocuments :many
+SELECT id, drive_file_id, filename, filepath, content, extension, last_modified, size_bytes FROM documents targets
+WHERE NOT EXISTS (
+  SELECT 1 FROM document_links
+  WHERE document_links.document_id <> targets.id AND (
+       (document_links.target_drive_file_id <> '' AND targets.drive_file_id = document_links.target_drive_file_id)
+    OR targets.filepath = document_links.target_path
+    OR (document_links.target_name <> '' AND lower(targets.filename) IN (document_links.target_name, document_links.target_name || targets.extension)))
 )
+ORDER BY filepath;
+
+-- name: CreateDocumentEntity :exec
+INSERT INTO document_entities (
+  document_id, type, value, mentions
+) VALUES (
+  ?, ?, ?, ?
+);
+
+-- name: CreateDocumentLanguage :exec
+INSERT INTO document_languages (
+  document_id, language
+) VALUES (
+  ?, ?
+);
+
+-- name: GetDocumentLanguage :one
+SELECT language FROM document_languages
+WHERE document_id = ?;
+
+-- name: CreateDocumentSummary :exec
+INSERT INTO document_summaries (
+  document_id, summary
+) VALUES (
+  ?, ?
+);
+
+-- name: GetDocumentSummary :one
+SELECT summary FROM document_summaries
+WHERE document_id = ?;
+
+-- name: CreateDocumentKeyword :exec
+INSERT INTO document_keywords (
+  document_id, keyword, weight
+) VALUES (
+  ?, ?, ?
+);
+
+-- name: DeleteDocumentKeywords :exec
+DELETE FROM document_keywords
+WHERE document_id = ?;
+
+-- name: ListDocumentContents :many
+SELECT id, content FROM documents
+WHERE id > sqlc.arg(after_id)
+ORDER BY id
 LIMIT sqlc.arg(limit);
 
+-- name: ListDocumentKeywords :many
+SELECT keyword FROM document_keywords
+WHERE document_id = ?
+ORDER BY weight DESC, keyword;
+
+-- name: ListTopEntities :many
+SELECT type, value, COUNT(*) AS documents, CAST(SUM(mentions) AS INTEGER) AS mentions
+FROM document_entities
+WHERE sqlc.arg(type) = '' OR type = sqlc.arg(type)
+GROUP BY type, value
+ORDER BY documents DESC, mentions DESC, type, value
+LIMIT sqlc.arg(limit);
+
+-- name: DeleteDocumentVersions :exec
+DELETE FROM document_versions
+WHERE drive_file_id = ?;
+
+-- name: DeleteAllDocumentVersions :exec
+DELETE FROM document_versions;
+
 -- name: DeleteAllDocuments :exec
 DELETE FROM documents;
@@ -0,0 +1,510 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"injestion-pipeline/language"
+)
+
+// ParseError reports where in the input a query stopped making sense.
+type ParseError struct {
+	Input string
+	// Pos is the rune offset of the problem within Input.
+	Pos int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid query: %s (at position %d)", e.Msg, e.Pos+1)
+}
+
+// Pointer renders the input with a caret under the offending position.
+func (e *ParseError) Pointer() string {
+	return e.Input + "\n" + strings.Repeat(" ", e.Pos) + "^"
+}
+
+// filterFields are the metadata keys that become SQL filters instead of
+// full-text terms.
+var filterFields = map[string]bool{
+	"ext":      true,
+	"path":     true,
+	"modified": true,
+	"created":  true,
+	"size":     true,
+	"owner":    true,
+	"starred":  true,
+	"entity":   true,
+	"lang":     true,
+}
+
+var sizeUnits = map[string]int64{
+	"":   1,
+	"b":  1,
+	"k":  1 << 10,
+	"kb": 1 << 10,
+	"m":  1 << 20,
+	"mb": 1 << 20,
+	"g":  1 << 30,
+	"gb": 1 << 30,
+}
+
+var sizePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-z]*)$`)
+
+type token struct {
+	pos    int
+	negate bool
+	key    string
+	value  string
+	quoted bool
+	prefix bool
+}
+
+// isFilter reports whether the token is a metadata filter. path: is a
+// folder glob when its value is absolute, and a full-text term otherwise.
+// Any other key that is not a field matches front matter.
+func (t token) isFilter() bool {
+	if t.key == "path" {
+		return strings.HasPrefix(t.value, "/")
+	}
+	_, field := fieldAliases[t.key]
+	return t.key != "" && !field
+}
+
+type parser struct {
+	input []rune
+	i     int
+	// frontMatter holds the front matter keys that can be filtered on.
+	frontMatter map[string]bool
+}
+
+// isKey reports whether key, lowercased, is a field, a filter or a front
+// matter key rather than part of a word such as "TODO:fix".
+func (p *parser) isKey(key string) bool {
+	_, field := fieldAliases[key]
+	return field || filterFields[key] || p.frontMatter[key]
+}
+
+// Parse reads a search query. Supported syntax:
+//
+//	deploy              word, matched in any field
+//	"connection reset"  phrase
+//	deploy*             prefix
+//	-draft              exclude documents containing a term
+//	deploy OR release   either term
+//	title:runbook       term scoped to a field (title, path, body)
+//	ext:md,txt          extension filter
+//	path:/eng/**        folder glob; * stays within a folder, ** crosses them
+//	modified:>2025-01-01
+//	created:<2024
+//	size:<10kb
+//	owner:alice@        owner whose email or name starts with the value
+//	starred:true
+//	entity:jira=OPS-42  document naming the entity; entity:OPS-42 for any type
+//	lang:de             document detected to be in a language: en, de or uk
+//	status:draft        front matter, for any key in frontMatter
+//
+// Other words with a colon, such as error:timeout, are search terms, unless
+// the value starts with a comparison and so must be a mistyped filter.
+func Parse(input string, frontMatter []string) (*Query, error) {
+	p := &parser{input: []rune(input), frontMatter: make(map[string]bool, len(frontMatter))}
+	for _, key := range frontMatter {
+		// tag: filters on tags as well, see the front matter filter.
+		key = strings.ToLower(key)
+		p.frontMatter[key] = true
+		p.frontMatter[strings.TrimSuffix(key, "s")] = true
+	}
+	q := &Query{}
+
+	var group []Term
+	pendingOr := false
+
+	flush := func() {
+		if len(group) > 0 {
+			q.Groups = append(q.Groups, group)
+			group = nil
+		}
+	}
+
+	for {
+		tok, ok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		if tok.value == "OR" && tok.key == "" && !tok.quoted && !tok.negate && !tok.prefix {
+			if len(group) == 0 || pendingOr {
+				return nil, p.errorAt(tok.pos, "OR must sit between two search terms")
+			}
+			pendingOr = true
+			continue
+		}
+
+		if tok.isFilter() {
+			if pendingOr {
+				return nil, p.errorAt(tok.pos, fmt.Sprintf("%s: filters cannot be combined with OR", tok.key))
+			}
+			filter, err := p.filter(tok)
+			if err != nil {
+				return nil, err
+			}
+			q.Filters = append(q.Filters, filter)
+			continue
+		}
+
+		term := Term{
+			Text:   tok.value,
+			Field:  fieldAliases[tok.key],
+			Phrase: tok.quoted,
+			Prefix: tok.prefix,
+		}
+
+		if tok.negate {
+			if pendingOr {
+				return nil, p.errorAt(tok.pos, "an excluded term cannot be combined with OR")
+			}
+			q.Exclude = append(q.Exclude, term)
+			continue
+		}
+
+		if pendingOr {
+			group = append(group, term)
+			pendingOr = false
+			continue
+		}
+
+		flush()
+		group = []Term{term}
+	}
+
+	if pendingOr {
+		return nil, p.errorAt(len(p.input), "OR must sit between two search terms")
+	}
+	flush()
+
+	if len(q.Groups) == 0 && len(q.Exclude) == 0 && len(q.Filters) == 0 {
+		return nil, p.errorAt(0, "query is empty")
+	}
+
+	return q, nil
+}
+
+// next reads the following whitespace-separated token. ok is false once
+// the input is exhausted.
+func (p *parser) next() (tok token, ok bool, err error) {
+	for p.i < len(p.input) && unicode.IsSpace(p.input[p.i]) {
+		p.i++
+	}
+	if p.i >= len(p.input) {
+		return tok, false, nil
+	}
+
+	tok.pos = p.i
+	if p.input[p.i] == '-' && p.i+1 < len(p.input) && !unicode.IsSpace(p.input[p.i+1]) {
+		tok.negate = true
+		p.i++
+	}
+
+	if p.peek() == '"' {
+		tok.value, err = p.quoted()
+		if err != nil {
+			return tok, false, err
+		}
+		tok.quoted = true
+	} else {
+		start := p.i
+		word := p.word()
+
+		key, value, found := strings.Cut(word, ":")
+		found = found && isIdentifier(key) && !strings.HasPrefix(value, "//")
+		if found && !p.isKey(strings.ToLower(key)) {
+			if strings.IndexAny(value, "<>=") == 0 {
+				return tok, false, p.errorAt(start, fmt.Sprintf("unknown field %q (expected one of %s, or a front matter key)", key, knownFields()))
+			}
+			found = false
+		}
+
+		if found {
+			tok.key = strings.ToLower(key)
+
+			switch {
+			case value != "":
+				tok.value = value
+			case p.peek() == '"':
+				tok.value, err = p.quoted()
+				if err != nil {
+					return tok, false, err
+				}
+				tok.quoted = true
+			default:
+				return tok, false, p.errorAt(p.i, fmt.Sprintf("missing value after %q", key+":"))
+			}
+		} else {
+			tok.value = word
+		}
+
+		if p.peek() == '"' {
+			return tok, false, p.errorAt(p.i, "unexpected quote inside a word")
+		}
+	}
+
+	if p.peek() == '*' {
+		tok.prefix = true
+		p.i++
+	} else if !tok.quoted && strings.HasSuffix(tok.value, "*") && !tok.isFilter() {
+		tok.prefix = true
+		tok.value = strings.TrimSuffix(tok.value, "*")
+	}
+
+	if tok.value == "" || strings.Trim(tok.value, "*") == "" {
+		return tok, false, p.errorAt(tok.pos, "expected a search term")
+	}
+
+	if p.i < len(p.input) && !unicode.IsSpace(p.input[p.i]) {
+		return tok, false, p.errorAt(p.i, fmt.Sprintf("unexpected %q", p.input[p.i]))
+	}
+
+	return tok, true, nil
+}
+
+func (p *parser) word() string {
+	start := p.i
+	for p.i < len(p.input) && !unicode.IsSpace(p.input[p.i]) && p.input[p.i] != '"' {
+		p.i++
+	}
+	return string(p.input[start:p.i])
+}
+
+func (p *parser) quoted() (string, error) {
+	open := p.i
+	p.i++
+
+	start := p.i
+	for p.i < len(p.input) && p.input[p.i] != '"' {
+		p.i++
+	}
+	if p.i >= len(p.input) {
+		return "", p.errorAt(open, "unterminated quote")
+	}
+
+	value := string(p.input[start:p.i])
+	p.i++
+
+	if strings.TrimSpace(value) == "" {
+		return "", p.errorAt(open, "empty phrase")
+	}
+	return value, nil
+}
+
+func (p *parser) peek() rune {
+	if p.i < len(p.input) {
+		return p.input[p.i]
+	}
+	return 0
+}
+
+func (p *parser) errorAt(pos int, msg string) *ParseError {
+	return &ParseError{Input: string(p.input), Pos: pos, Msg: msg}
+}
+
+func (p *parser) filter(tok token) (Filter, error) {
+	f := Filter{Field: tok.key, Op: "=", Negate: tok.negate}
+	value := tok.value
+
+	switch tok.key {
+	case "ext":
+		for _, ext := range strings.Split(value, ",") {
+			ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+			if ext == "" {
+				return f, p.errorAt(tok.pos, "ext: expected an extension such as ext:md")
+			}
+			f.Values = append(f.Values, "."+ext)
+		}
+
+	case "path":
+		f.Pattern = globPattern(value)
+
+	case "modified", "created":
+		f.Op, value = splitOperator(value)
+		from, until, err := parseDate(value)
+		if err != nil {
+			return f, p.errorAt(tok.pos, fmt.Sprintf("%s: %q is not a date, use YYYY-MM-DD", tok.key, value))
+		}
+		f.From, f.Until = from, until
+
+	case "size":
+		f.Op, value = splitOperator(value)
+		size, err := parseSize(value)
+		if err != nil {
+			return f, p.errorAt(tok.pos, fmt.Sprintf("size: %q is not a size, use e.g. 10kb or 2mb", value))
+		}
+		f.Size = size
+
+	case "owner":
+		for _, owner := range strings.Split(value, ",") {
+			owner = strings.ToLower(strings.TrimSpace(owner))
+			if owner == "" {
+				return f, p.errorAt(tok.pos, "owner: expected an email or name such as owner:alice@")
+			}
+			f.Values = append(f.Values, owner)
+		}
+
+	case "starred":
+		starred, err := strconv.ParseBool(value)
+		if err != nil {
+			return f, p.errorAt(tok.pos, fmt.Sprintf("starred: %q is not true or false", value))
+		}
+		f.Flag = starred
+
+	case "entity":
+		entityType, values, found := strings.Cut(value, "=")
+		if !found {
+			entityType, values = "", value
+		}
+		f.Key = strings.ToLower(strings.TrimSpace(entityType))
+		for _, v := range strings.Split(values, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				return f, p.errorAt(tok.pos, "entity: expected a value such as entity:jira=OPS-42")
+			}
+			f.Values = append(f.Values, v)
+		}
+
+	case "lang":
+		for _, lang := range strings.Split(value, ",") {
+			lang = strings.ToLower(strings.TrimSpace(lang))
+			if !language.Supported(lang) {
+				return f, p.errorAt(tok.pos, fmt.Sprintf("lang: %q is not a language, use one of %s", lang, strings.Join(language.Languages, ", ")))
+			}
+			f.Values = append(f.Values, lang)
+		}
+
+	default:
+		f.Field = "frontmatter"
+		f.Key = tok.key
+		for _, v := range strings.Split(value, ",") {
+			v = strings.ToLower(strings.TrimSpace(v))
+			if v == "" {
+				return f, p.errorAt(tok.pos, fmt.Sprintf("%s: expected a value", tok.key))
+			}
+			f.Values = append(f.Values, v)
+		}
+	}
+
+	return f, nil
+}
+
+func splitOperator(value string) (string, string) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, op) {
+			return op, strings.TrimPrefix(value, op)
+		}
+	}
+	return "=", value
+}
+
+// parseDate accepts a year, month, day or full timestamp and returns the
+// interval it covers.
+func parseDate(value string) (time.Time, time.Time, error) {
+	layouts := []struct {
+		layout string
+		next   func(time.Time) time.Time
+	}{
+		{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+		{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+		{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+		{time.RFC3339, func(t time.Time) time.Time { return t.Add(time.Second) }},
+	}
+
+	for _, l := range layouts {
+		if t, err := time.Parse(l.layout, value); err == nil {
+			return t, l.next(t), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+func parseSize(value string) (int64, error) {
+	m := sizePattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(value)))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+
+	unit, ok := sizeUnits[m[2]]
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit %q", m[2])
+	}
+
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// globPattern compiles a folder glob. A pattern without wildcards matches
+// the path itself and everything below it.
+func globPattern(glob string) *regexp.Regexp {
+	if !strings.ContainsAny(glob, "*?") {
+		prefix := regexp.QuoteMeta(strings.TrimSuffix(glob, "/"))
+		return regexp.MustCompile("^" + prefix + "(/.*)?$")
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String())
+}
+
+// isIdentifier reports whether s can be a field or front matter key: a
+// letter followed by letters, digits, '_', '-' or '.'.
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case unicode.IsLetter(r), r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func knownFields() string {
+	var names []string
+	for name := range fieldAliases {
+		names = append(names, name)
+	}
+	for name := range filterFields {
+		if _, dup := fieldAliases[name]; !dup {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,365 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTerms(t *testing.T) {
+	tests := []struct {
+		input   string
+		groups  [][]Term
+		exclude []Term
+	}{
+		{
+			input:  "deploy",
+			groups: [][]Term{{{Text: "deploy"}}},
+		},
+		{
+			input:  `"connection reset" timeout`,
+			groups: [][]Term{{{Text: "connection reset", Phrase: true}}, {{Text: "timeout"}}},
+		},
+		{
+			input:  "deploy OR release rollback",
+			groups: [][]Term{{{Text: "deploy"}, {Text: "release"}}, {{Text: "rollback"}}},
+		},
+		{
+			input:  "deploy OR release OR ship",
+			groups: [][]Term{{{Text: "deploy"}, {Text: "release"}, {Text: "ship"}}},
+		},
+		{
+			// Only the uppercase keyword joins terms.
+			input:  "deploy or release",
+			groups: [][]Term{{{Text: "deploy"}}, {{Text: "or"}}, {{Text: "release"}}},
+		},
+		{
+			input:   "deploy -draft",
+			groups:  [][]Term{{{Text: "deploy"}}},
+			exclude: []Term{{Text: "draft"}},
+		},
+		{
+			input:   `-"work in progress"`,
+			exclude: []Term{{Text: "work in progress", Phrase: true}},
+		},
+		{
+			input:  "pre-release",
+			groups: [][]Term{{{Text: "pre-release"}}},
+		},
+		{
+			input:  "depl*",
+			groups: [][]Term{{{Text: "depl", Prefix: true}}},
+		},
+		{
+			input:  `"connection res"*`,
+			groups: [][]Term{{{Text: "connection res", Phrase: true, Prefix: true}}},
+		},
+		{
+			input:   "-depl*",
+			exclude: []Term{{Text: "depl", Prefix: true}},
+		},
+		{
+			input:  "title:runbook",
+			groups: [][]Term{{{Text: "runbook", Field: "filename"}}},
+		},
+		{
+			input:  "NAME:runbook",
+			groups: [][]Term{{{Text: "runbook", Field: "filename"}}},
+		},
+		{
+			input:  "body:timeout content:reset",
+			groups: [][]Term{{{Text: "timeout", Field: "content"}}, {{Text: "reset", Field: "content"}}},
+		},
+		{
+			input:  `folder:"on call"`,
+			groups: [][]Term{{{Text: "on call", Field: "path", Phrase: true}}},
+		},
+		{
+			// A relative path is a term, an absolute one a folder glob.
+			input:  "path:runbooks",
+			groups: [][]Term{{{Text: "runbooks", Field: "path"}}},
+		},
+		{
+			input:  "title:run*",
+			groups: [][]Term{{{Text: "run", Field: "filename", Prefix: true}}},
+		},
+		{
+			input:  "error:timeout TODO:fix",
+			groups: [][]Term{{{Text: "error:timeout"}}, {{Text: "TODO:fix"}}},
+		},
+		{
+			input:  "https://example.com/runbook",
+			groups: [][]Term{{{Text: "https://example.com/runbook"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := Parse(tt.input, nil)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(q.Groups, tt.groups) {
+				t.Errorf("groups = %+v, want %+v", q.Groups, tt.groups)
+			}
+			if !reflect.DeepEqual(q.Exclude, tt.exclude) {
+				t.Errorf("exclude = %+v, want %+v", q.Exclude, tt.exclude)
+			}
+			if len(q.Filters) != 0 {
+				t.Errorf("filters = %+v, want none", q.Filters)
+			}
+		})
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		input  string
+		filter Filter
+	}{
+		{"ext:md", Filter{Field: "ext", Op: "=", Values: []string{".md"}}},
+		{"ext:md,.TXT", Filter{Field: "ext", Op: "=", Values: []string{".md", ".txt"}}},
+		{"-ext:pdf", Filter{Field: "ext", Op: "=", Negate: true, Values: []string{".pdf"}}},
+		{"modified:2025-03-14", Filter{Field: "modified", Op: "=", From: day("2025-03-14"), Until: day("2025-03-15")}},
+		{"modified:>2025-01-01", Filter{Field: "modified", Op: ">", From: day("2025-01-01"), Until: day("2025-01-02")}},
+		{"modified:>=2025-03", Filter{Field: "modified", Op: ">=", From: day("2025-03-01"), Until: day("2025-04-01")}},
+		{"created:<2024", Filter{Field: "created", Op: "<", From: day("2024-01-01"), Until: day("2025-01-01")}},
+		{"size:100", Filter{Field: "size", Op: "=", Size: 100}},
+		{"size:<10kb", Filter{Field: "size", Op: "<", Size: 10 << 10}},
+		{"size:>=1.5MB", Filter{Field: "size", Op: ">=", Size: 3 << 19}},
+		{"size:>2g", Filter{Field: "size", Op: ">", Size: 2 << 30}},
+		{"owner:Alice@,bob", Filter{Field: "owner", Op: "=", Values: []string{"alice@", "bob"}}},
+		{"starred:true", Filter{Field: "starred", Op: "=", Flag: true}},
+		{"starred:false", Filter{Field: "starred", Op: "="}},
+		{"entity:jira=OPS-42", Filter{Field: "entity", Op: "=", Key: "jira", Values: []string{"OPS-42"}}},
+		{"entity:OPS-42,OPS-43", Filter{Field: "entity", Op: "=", Values: []string{"OPS-42", "OPS-43"}}},
+		{"lang:DE,en", Filter{Field: "lang", Op: "=", Values: []string{"de", "en"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := Parse(tt.input, nil)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.input, err)
+			}
+			if len(q.Filters) != 1 {
+				t.Fatalf("filters = %+v, want one", q.Filters)
+			}
+			if got := q.Filters[0]; !reflect.DeepEqual(got, tt.filter) {
+				t.Errorf("filter = %+v, want %+v", got, tt.filter)
+			}
+			if len(q.Groups) != 0 || len(q.Exclude) != 0 {
+				t.Errorf("terms = %+v, %+v, want none", q.Groups, q.Exclude)
+			}
+		})
+	}
+}
+
+func TestParsePathGlob(t *testing.T) {
+	tests := []struct {
+		input string
+		match []string
+		miss  []string
+	}{
+		{
+			input: "path:/eng",
+			match: []string{"/eng", "/eng/runbook.md", "/eng/oncall/failover.md"},
+			miss:  []string{"/engineering/runbook.md", "/ops/eng/runbook.md"},
+		},
+		{
+			input: "path:/eng/",
+			match: []string{"/eng", "/eng/runbook.md"},
+			miss:  []string{"/engineering"},
+		},
+		{
+			input: "path:/eng/*",
+			match: []string{"/eng/runbook.md"},
+			miss:  []string{"/eng/oncall/failover.md", "/ops/runbook.md"},
+		},
+		{
+			input: "path:/eng/**",
+			match: []string{"/eng/runbook.md", "/eng/oncall/failover.md"},
+			miss:  []string{"/ops/runbook.md"},
+		},
+		{
+			input: "path:/eng/**/*.md",
+			match: []string{"/eng/oncall/failover.md"},
+			miss:  []string{"/eng/oncall/failover.txt"},
+		},
+		{
+			input: "path:/eng/v?.md",
+			match: []string{"/eng/v1.md"},
+			miss:  []string{"/eng/v10.md", "/eng/v/.md"},
+		},
+		{
+			input: "path:/eng/a.b(1)",
+			match: []string{"/eng/a.b(1)"},
+			miss:  []string{"/eng/axb1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := Parse(tt.input, nil)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.input, err)
+			}
+			if len(q.Filters) != 1 || q.Filters[0].Field != "path" {
+				t.Fatalf("filters = %+v, want one path filter", q.Filters)
+			}
+			pattern := q.Filters[0].Pattern
+			for _, path := range tt.match {
+				if !pattern.MatchString(path) {
+					t.Errorf("%s does not match %s", pattern, path)
+				}
+			}
+			for _, path := range tt.miss {
+				if pattern.MatchString(path) {
+					t.Errorf("%s matches %s", pattern, path)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	keys := []string{"status", "Tags", "review-date"}
+
+	tests := []struct {
+		input  string
+		filter *Filter
+		terms  [][]Term
+	}{
+		{
+			input:  "status:draft",
+			filter: &Filter{Field: "frontmatter", Op: "=", Key: "status", Values: []string{"draft"}},
+		},
+		{
+			input:  "STATUS:Draft,Review",
+			filter: &Filter{Field: "frontmatter", Op: "=", Key: "status", Values: []string{"draft", "review"}},
+		},
+		{
+			input:  "-status:archived",
+			filter: &Filter{Field: "frontmatter", Op: "=", Negate: true, Key: "status", Values: []string{"archived"}},
+		},
+		{
+			input:  "tags:infra",
+			filter: &Filter{Field: "frontmatter", Op: "=", Key: "tags", Values: []string{"infra"}},
+		},
+		{
+			// A plural key can be filtered on by its singular too.
+			input:  "tag:infra",
+			filter: &Filter{Field: "frontmatter", Op: "=", Key: "tag", Values: []string{"infra"}},
+		},
+		{
+			input:  `review-date:"next week"`,
+			filter: &Filter{Field: "frontmatter", Op: "=", Key: "review-date", Values: []string{"next week"}},
+		},
+		{
+			input: "priority:high",
+			terms: [][]Term{{{Text: "priority:high"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := Parse(tt.input, keys)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.input, err)
+			}
+			if tt.filter == nil {
+				if len(q.Filters) != 0 {
+					t.Errorf("filters = %+v, want none", q.Filters)
+				}
+			} else if len(q.Filters) != 1 || !reflect.DeepEqual(q.Filters[0], *tt.filter) {
+				t.Errorf("filters = %+v, want %+v", q.Filters, *tt.filter)
+			}
+			if !reflect.DeepEqual(q.Groups, tt.terms) {
+				t.Errorf("groups = %+v, want %+v", q.Groups, tt.terms)
+			}
+		})
+	}
+
+	// Without the key among the front matter, the same word is a term.
+	q, err := Parse("status:draft", nil)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(q.Filters) != 0 || !reflect.DeepEqual(q.Groups, [][]Term{{{Text: "status:draft"}}}) {
+		t.Errorf("status:draft without front matter = %+v, %+v, want a term", q.Groups, q.Filters)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+		msg   string
+	}{
+		{"", 0, "query is empty"},
+		{"   ", 0, "query is empty"},
+		{`"unterminated`, 0, "unterminated quote"},
+		{`deploy "unterminated`, 7, "unterminated quote"},
+		{`deploy ""`, 7, "empty phrase"},
+		{`dep"loy"`, 3, "unexpected quote inside a word"},
+		{`"reset"now`, 7, `unexpected 'n'`},
+		{"*", 0, "expected a search term"},
+		{"OR deploy", 0, "OR must sit between two search terms"},
+		{"deploy OR", 9, "OR must sit between two search terms"},
+		{"deploy OR OR release", 10, "OR must sit between two search terms"},
+		{"deploy OR -draft", 10, "an excluded term cannot be combined with OR"},
+		{"deploy OR ext:md", 10, "filters cannot be combined with OR"},
+		{"title:", 6, `missing value after "title:"`},
+		{"deploy modifed:>2025-01-01", 7, `unknown field "modifed"`},
+		{"modified:yesterday", 0, `"yesterday" is not a date`},
+		{"deploy size:<10zb", 7, `"10zb" is not a size`},
+		{"ext:,", 0, "expected an extension"},
+		{"starred:maybe", 0, `"maybe" is not true or false`},
+		{"entity:jira=", 0, "expected a value"},
+		{"lang:fr", 0, `"fr" is not a language`},
+		// Positions count runes, not bytes.
+		{`café "reset`, 5, "unterminated quote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input, nil)
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("Parse(%q) error = %v, want a ParseError", tt.input, err)
+			}
+			if perr.Pos != tt.pos {
+				t.Errorf("pos = %d, want %d\n%s", perr.Pos, tt.pos, perr.Pointer())
+			}
+			if !strings.Contains(perr.Msg, tt.msg) {
+				t.Errorf("msg = %q, want it to contain %q", perr.Msg, tt.msg)
+			}
+		})
+	}
+}
+
+func TestParseErrorPointer(t *testing.T) {
+	_, err := Parse(`café "reset`, nil)
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("error = %v, want a ParseError", err)
+	}
+
+	want := "café \"reset\n     ^"
+	if got := perr.Pointer(); got != want {
+		t.Errorf("Pointer() = %q, want %q", got, want)
+	}
+	if got := perr.Error(); !strings.HasSuffix(got, "(at position 6)") {
+		t.Errorf("Error() = %q, want the position counted from 1", got)
+	}
+}
@@ -0,0 +1,187 @@
+package query
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// fieldAliases maps the field names users type onto documents_fts columns.
+var fieldAliases = map[string]string{
+	"title":    "filename",
+	"name":     "filename",
+	"filename": "filename",
+	"path":     "path",
+	"folder":   "path",
+	"body":     "content",
+	"content":  "content",
+}
+
+// Term is a single word or phrase to look up in the full-text index.
+type Term struct {
+	Text string
+	// Field restricts the term to one documents_fts column. Empty means
+	// every column.
+	Field  string
+	Phrase bool
+	Prefix bool
+}
+
+// Filter restricts results by document metadata rather than by content.
+type Filter struct {
+	Field  string
+	Op     string
+	Negate bool
+
+	// Values holds the accepted extensions for "ext", each with a leading
+	// dot, the lowercased owner prefixes for "owner", the lowercased values
+	// accepted for a "frontmatter" key, the entity values of an "entity"
+	// filter, or the language codes of a "lang" filter.
+	Values []string
+	// Key is the front matter key a "frontmatter" filter looks at, or the
+	// lowercased entity type of an "entity" filter, empty for any type.
+	Key string
+	// Pattern is the compiled glob for "path".
+	Pattern *regexp.Regexp
+	// From and Until bound the date given to "modified" or "created": From
+	// is the start of the day, month or year typed, Until the start of the
+	// next one.
+	From  time.Time
+	Until time.Time
+	// Size is the byte count given to "size".
+	Size int64
+	// Flag is the value given to "starred".
+	Flag bool
+}
+
+// Query is a parsed search. A document matches when it satisfies every
+// group (each group matches when any of its terms does), none of the
+// excluded terms, and every filter.
+type Query struct {
+	Groups  [][]Term
+	Exclude []Term
+	Filters []Filter
+}
+
+// Text returns the words of the positive terms, for retrievers that work
+// on free text rather than on MATCH expressions.
+func (q *Query) Text() string {
+	var words []string
+	for _, group := range q.Groups {
+		for _, t := range group {
+			words = append(words, t.Text)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Match returns the FTS5 MATCH expression for the query, or an empty
+// string when there are no positive terms to match on. Every term is
+// emitted as a quoted string, so user input can never be read as FTS5
+// syntax.
+func (q *Query) Match() string {
+	if len(q.Groups) == 0 {
+		return ""
+	}
+
+	groups := make([]string, 0, len(q.Groups))
+	for _, group := range q.Groups {
+		groups = append(groups, orExpr(group))
+	}
+	match := strings.Join(groups, " AND ")
+
+	if len(q.Exclude) > 0 {
+		match = "(" + match + ") NOT " + orExpr(q.Exclude)
+	}
+
+	return match
+}
+
+// MatchIn returns Match with every term the user did not scope to a field
+// scoped to column instead.
+func (q *Query) MatchIn(column string) string {
+	scoped := &Query{
+		Groups:  make([][]Term, 0, len(q.Groups)),
+		Exclude: scopeTerms(q.Exclude, column),
+	}
+	for _, group := range q.Groups {
+		scoped.Groups = append(scoped.Groups, scopeTerms(group, column))
+	}
+	return scoped.Match()
+}
+
+func scopeTerms(terms []Term, column string) []Term {
+	scoped := make([]Term, 0, len(terms))
+	for _, t := range terms {
+		if t.Field == "" {
+			t.Field = column
+		}
+		scoped = append(scoped, t)
+	}
+	return scoped
+}
+
+// StemmedMatch returns Match with the words of each term replaced by
+// what stem makes of them, for matching against stemmed text. Prefix terms
+// and terms scoped to the path are kept as typed, because a prefix is
+// already a partial word and paths are indexed unstemmed.
+func (q *Query) StemmedMatch(stem func(string) string) string {
+	stemmed := &Query{
+		Groups:  make([][]Term, 0, len(q.Groups)),
+		Exclude: stemTerms(q.Exclude, stem),
+	}
+	for _, group := range q.Groups {
+		stemmed.Groups = append(stemmed.Groups, stemTerms(group, stem))
+	}
+	return stemmed.Match()
+}
+
+func stemTerms(terms []Term, stem func(string) string) []Term {
+	stemmed := make([]Term, 0, len(terms))
+	for _, t := range terms {
+		if !t.Prefix && t.Field != "path" {
+			if text := stem(t.Text); text != "" {
+				t.Text = text
+			}
+		}
+		stemmed = append(stemmed, t)
+	}
+	return stemmed
+}
+
+// ExcludeMatch returns an FTS5 expression matching any excluded term. It
+// is only needed when the query has no positive terms, since Match folds
+// the exclusions in with NOT otherwise.
+func (q *Query) ExcludeMatch() string {
+	if len(q.Exclude) == 0 {
+		return ""
+	}
+	return orExpr(q.Exclude)
+}
+
+func orExpr(terms []Term) string {
+	parts := make([]string, 0, len(terms))
+	for _, t := range terms {
+		parts = append(parts, t.expr())
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+func (t Term) expr() string {
+	expr := quote(t.Text)
+	if t.Prefix {
+		expr += "*"
+	}
+	if t.Field != "" {
+		expr = t.Field + " : " + expr
+	}
+	return expr
+}
+
+// quote renders s as an FTS5 string literal.
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
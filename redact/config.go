@@ -0,0 +1,87 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Config changes the mode of built-in detectors and adds custom rules.
+type Config struct {
+	// Detectors maps built-in detector names to their mode.
+	Detectors map[string]string `json:"detectors"`
+	Rules     []Rule            `json:"rules"`
+}
+
+// Rule is a custom detector. Mode defaults to mask.
+type Rule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Mode    string `json:"mode"`
+}
+
+// LoadConfig reads a JSON configuration file such as
+//
+//	{
+//	  "detectors": {"email": "flag", "private_key": "drop"},
+//	  "rules": [
+//	    {"name": "customer_id", "pattern": "CUST-[0-9]{6}", "mode": "mask"}
+//	  ]
+//	}
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction config: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction config %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// Build returns the built-in detectors with the configured modes, followed
+// by the custom rules. A nil config gives the built-in detectors as they
+// are.
+func (c *Config) Build() ([]*Detector, error) {
+	detectors := Builtin()
+	if c == nil {
+		return detectors, nil
+	}
+
+	byName := make(map[string]*Detector, len(detectors))
+	for _, d := range detectors {
+		byName[d.Name] = d
+	}
+	for name, value := range c.Detectors {
+		d, ok := byName[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown redaction detector %q", name)
+		}
+		mode, err := parseMode(value)
+		if err != nil {
+			return nil, fmt.Errorf("detector %s: %w", name, err)
+		}
+		d.Mode = mode
+	}
+
+	for _, rule := range c.Rules {
+		if rule.Name == "" || rule.Pattern == "" {
+			return nil, fmt.Errorf("redaction rules need a name and a pattern")
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		mode, err := parseMode(rule.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		detectors = append(detectors, &Detector{Name: rule.Name, Pattern: re, Mode: mode})
+	}
+
+	return detectors, nil
+}
@@ -0,0 +1,300 @@
+// Package redact finds secrets and personal data in documents before they
+// are indexed, and masks them, drops the document or flags it for review.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"injestion-pipeline/models"
+)
+
+// Mode is what happens to a document when a detector matches in it.
+type Mode string
+
+const (
+	// ModeMask replaces each match with a [REDACTED:<detector>] marker.
+	ModeMask Mode = "mask"
+	// ModeDrop leaves the whole document out of the index.
+	ModeDrop Mode = "drop"
+	// ModeFlag stores the document unchanged and lists it in the report.
+	ModeFlag Mode = "flag"
+	// ModeOff turns the detector off.
+	ModeOff Mode = "off"
+)
+
+func parseMode(s string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case ModeMask, ModeDrop, ModeFlag, ModeOff:
+		return mode, nil
+	case "":
+		return ModeMask, nil
+	}
+	return "", fmt.Errorf("unknown redaction mode %q, expected mask, drop, flag or off", s)
+}
+
+// Detector finds one kind of sensitive text. When Pattern has a group,
+// only the group is masked, so "aws_secret_access_key = ..." keeps its
+// name.
+type Detector struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Valid, when set, confirms a match, such as by the checksum of a card
+	// number.
+	Valid func(string) bool
+	Mode  Mode
+}
+
+// Builtin returns the detectors for AWS keys, JWTs, private keys, card
+// numbers and email addresses, all in mask mode.
+func Builtin() []*Detector {
+	return []*Detector{
+		{Name: "private_key", Pattern: privateKey, Mode: ModeMask},
+		{Name: "aws_access_key", Pattern: awsAccessKey, Mode: ModeMask},
+		{Name: "aws_secret_key", Pattern: awsSecretKey, Mode: ModeMask},
+		{Name: "jwt", Pattern: jwt, Mode: ModeMask},
+		{Name: "card_number", Pattern: cardNumber, Valid: luhn, Mode: ModeMask},
+		{Name: "email", Pattern: email, Mode: ModeMask},
+	}
+}
+
+var (
+	privateKey   = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)
+	awsAccessKey = regexp.MustCompile(`\b(?:AKIA|ASIA|AIDA|AROA|AGPA|ANPA)[A-Z0-9]{16}\b`)
+	awsSecretKey = regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`)
+	jwt          = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	cardNumber   = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	email        = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+)
+
+// luhn reports whether the digits in s pass the Luhn checksum that card
+// numbers carry.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && n <= 19 && sum%10 == 0
+}
+
+// Finding is where one detector matched in one part of a document.
+type Finding struct {
+	Detector string `json:"detector"`
+	Mode     Mode   `json:"mode"`
+	// Field is the part of the document, such as "content" or
+	// "comment <id>".
+	Field string `json:"field"`
+	Count int    `json:"count"`
+	// Lines are the 1-based lines of the field the matches start on.
+	Lines []int `json:"lines"`
+}
+
+// Result is what redacting a document found and did.
+type Result struct {
+	Findings []Finding
+	// Dropped means a detector in drop mode matched, so the document must
+	// not be stored.
+	Dropped bool
+}
+
+// Action sums up the result as "dropped", "masked", "flagged" or "" when
+// nothing was found.
+func (r Result) Action() string {
+	masked, flagged := false, false
+	for _, f := range r.Findings {
+		switch f.Mode {
+		case ModeMask:
+			masked = true
+		case ModeFlag:
+			flagged = true
+		}
+	}
+
+	switch {
+	case r.Dropped:
+		return "dropped"
+	case masked:
+		return "masked"
+	case flagged:
+		return "flagged"
+	}
+	return ""
+}
+
+// Redactor runs detectors over documents.
+type Redactor struct {
+	detectors []*Detector
+}
+
+func New(detectors []*Detector) *Redactor {
+	return &Redactor{detectors: detectors}
+}
+
+// Document runs every detector over the text of doc that gets stored: its
+// name and path, content, revisions and their authors, comments and their
+// authors, front matter, links, entities and metadata. Matches of detectors
+// in mask mode are replaced in place, and an entity with a masked match is
+// removed, since its value is gone. The name and path identify the file, so
+// they are never masked: a match there is flagged instead.
+func (r *Redactor) Document(doc *models.Document) Result {
+	var result Result
+	scan := func(field string, text *string) {
+		r.scan(&result, field, text)
+	}
+
+	r.check(&result, "name", doc.FileName)
+	r.check(&result, "path", doc.FilePath)
+	scan("content", &doc.Content)
+	for i := range doc.Revisions {
+		scan("revision "+doc.Revisions[i].ID, &doc.Revisions[i].Content)
+		scan("revision "+doc.Revisions[i].ID+" author", &doc.Revisions[i].Author)
+	}
+	for i := range doc.Comments {
+		scan("comment "+doc.Comments[i].ID, &doc.Comments[i].Content)
+		scan("comment "+doc.Comments[i].ID+" anchor", &doc.Comments[i].Anchor)
+		scan("comment "+doc.Comments[i].ID+" author", &doc.Comments[i].Author)
+	}
+	for i := range doc.FrontMatter {
+		scan("front matter "+doc.FrontMatter[i].Key, &doc.FrontMatter[i].Value)
+	}
+	for i := range doc.Links {
+		scan("link", &doc.Links[i].Target)
+		scan("link", &doc.Links[i].Text)
+	}
+	kept := doc.Entities[:0]
+	for _, e := range doc.Entities {
+		value := e.Value
+		scan("entity "+e.Type, &value)
+		if value == e.Value {
+			kept = append(kept, e)
+		}
+	}
+	doc.Entities = kept
+	r.metadata(&result, doc.Metadata)
+
+	return result
+}
+
+// Metadata runs every detector over the metadata of a file whose content
+// was not downloaded again, which is stored on its own.
+func (r *Redactor) Metadata(meta *models.Metadata) Result {
+	var result Result
+	r.metadata(&result, meta)
+	return result
+}
+
+// metadata scans the people and text Drive reports about a file. Its
+// permissions are left as they are, since search --as needs their
+// addresses to decide who can read it.
+func (r *Redactor) metadata(result *Result, meta *models.Metadata) {
+	if meta == nil {
+		return
+	}
+	for i := range meta.Owners {
+		r.scan(result, "owner", &meta.Owners[i].Name)
+		r.scan(result, "owner", &meta.Owners[i].Email)
+	}
+	r.scan(result, "last modifying user", &meta.LastModifyingUser)
+	r.scan(result, "description", &meta.Description)
+
+	keys := make([]string, 0, len(meta.AppProperties))
+	for key := range meta.AppProperties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := meta.AppProperties[key]
+		r.scan(result, "app property "+key, &value)
+		meta.AppProperties[key] = value
+	}
+}
+
+// check runs every detector over text that must be stored as it is. Matches
+// are reported, and detectors in drop mode drop the document, but matches of
+// detectors in mask mode are only flagged.
+func (r *Redactor) check(result *Result, field string, text string) {
+	for _, d := range r.detectors {
+		if d.Mode == ModeOff {
+			continue
+		}
+		finding, _ := d.apply(text)
+		if finding.Count == 0 {
+			continue
+		}
+		finding.Field = field
+		if d.Mode == ModeMask {
+			finding.Mode = ModeFlag
+		}
+		result.Findings = append(result.Findings, finding)
+		if d.Mode == ModeDrop {
+			result.Dropped = true
+		}
+	}
+}
+
+// scan runs every detector over text, recording what it finds in result
+// and masking text in place for detectors in mask mode.
+func (r *Redactor) scan(result *Result, field string, text *string) {
+	for _, d := range r.detectors {
+		if d.Mode == ModeOff {
+			continue
+		}
+		finding, masked := d.apply(*text)
+		if finding.Count == 0 {
+			continue
+		}
+		finding.Field = field
+		result.Findings = append(result.Findings, finding)
+		if d.Mode == ModeMask {
+			*text = masked
+		}
+		if d.Mode == ModeDrop {
+			result.Dropped = true
+		}
+	}
+}
+
+// apply finds the detector's matches in text and returns them with text
+// masked.
+func (d *Detector) apply(text string) (Finding, string) {
+	finding := Finding{Detector: d.Name, Mode: d.Mode}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range d.Pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if len(loc) > 2 && loc[2] >= 0 {
+			start, end = loc[2], loc[3]
+		}
+		if d.Valid != nil && !d.Valid(text[start:end]) {
+			continue
+		}
+
+		finding.Count++
+		line := strings.Count(text[:start], "\n") + 1
+		if n := len(finding.Lines); n == 0 || finding.Lines[n-1] != line {
+			finding.Lines = append(finding.Lines, line)
+		}
+
+		b.WriteString(text[last:start])
+		b.WriteString("[REDACTED:" + d.Name + "]")
+		last = end
+	}
+	b.WriteString(text[last:])
+
+	return finding, b.String()
+}
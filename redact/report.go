@@ -0,0 +1,67 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Report records what redaction found in a run. It names detectors and
+// lines, never the matched text.
+type Report struct {
+	Started   time.Time     `json:"started"`
+	Documents int           `json:"documents"`
+	Masked    int           `json:"masked"`
+	Dropped   int           `json:"dropped"`
+	Flagged   int           `json:"flagged"`
+	Entries   []ReportEntry `json:"entries"`
+}
+
+// ReportEntry is a document something was found in.
+type ReportEntry struct {
+	DriveFileID string    `json:"drive_file_id"`
+	Path        string    `json:"path"`
+	Action      string    `json:"action"`
+	Findings    []Finding `json:"findings"`
+}
+
+func NewReport() *Report {
+	return &Report{Started: time.Now().UTC(), Entries: []ReportEntry{}}
+}
+
+// Add counts a redacted document and records what was found in it.
+func (r *Report) Add(driveFileID, path string, result Result) {
+	r.Documents++
+
+	action := result.Action()
+	switch action {
+	case "":
+		return
+	case "dropped":
+		r.Dropped++
+	case "masked":
+		r.Masked++
+	case "flagged":
+		r.Flagged++
+	}
+
+	r.Entries = append(r.Entries, ReportEntry{
+		DriveFileID: driveFileID,
+		Path:        path,
+		Action:      action,
+		Findings:    result.Findings,
+	})
+}
+
+// Write saves the report as JSON.
+func (r *Report) Write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode redaction report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write redaction report: %w", err)
+	}
+	return nil
+}
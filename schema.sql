@@ -9,14 +9,17 @@ CREATE TABLE IF NOT EXISTS documents (
   size_bytes      INT NOT NULL
 );
 
+CREATE INDEX IF NOT EXISTS documents_drive_file_id ON documents(drive_file_id);
+
 CREATE VIRTUAL TABLE IF NOT EXISTS documents_fts USING fts5(
     filename,
+    path,
     content
 );
 
 CREATE TRIGGER IF NOT EXISTS documents_auto_insert AFTER INSERT ON documents BEGIN
-    INSERT INTO documents_fts(rowid, filename, content)
-    VALUES (new.id, new.filename, new.content);
+    INSERT INTO documents_fts(rowid, filename, path, content)
+    VALUES (new.id, new.filename, new.filepath, new.content);
 END;
 
 CREATE TRIGGER IF NOT EXISTS documents_auto_delete AFTER DELETE ON documents BEGIN
@@ -25,7 +28,317 @@ END;
 
 CREATE TRIGGER IF NOT EXISTS documents_auto_update AFTER UPDATE ON documents BEGIN
     UPDATE documents_fts
-    SET filename = new.filename, content = new.content
+    SET filename = new.filename, path = new.filepath, content = new.content
     WHERE rowid = new.id;
 END;
 
+-- Read-only views of the full-text index, used to find the terms that
+-- distinguish a document: per-document occurrences and document frequency.
+CREATE VIRTUAL TABLE IF NOT EXISTS documents_vocab USING fts5vocab(documents_fts, instance);
+CREATE VIRTUAL TABLE IF NOT EXISTS documents_vocab_rows USING fts5vocab(documents_fts, row);
+
+CREATE TABLE IF NOT EXISTS chunks (
+  id            INTEGER PRIMARY KEY,
+  document_id   INTEGER NOT NULL,
+  ordinal       INT NOT NULL,
+  heading       TEXT NOT NULL,
+  content       TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS chunks_document_id ON chunks(document_id);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS chunks_fts USING fts5(
+    filename,
+    path,
+    content
+);
+
+CREATE TRIGGER IF NOT EXISTS chunks_auto_insert AFTER INSERT ON chunks BEGIN
+    INSERT INTO chunks_fts(rowid, filename, path, content)
+    SELECT new.id, documents.filename, documents.filepath, new.content
+    FROM documents WHERE documents.id = new.document_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS chunks_auto_delete AFTER DELETE ON chunks BEGIN
+    DELETE FROM chunks_fts WHERE rowid = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS documents_delete_chunks AFTER DELETE ON documents BEGIN
+    DELETE FROM chunks WHERE document_id = old.id;
+END;
+
+CREATE TABLE IF NOT EXISTS chunk_vectors (
+  chunk_id      INTEGER PRIMARY KEY,
+  model         TEXT NOT NULL,
+  dims          INT NOT NULL,
+  vector        BLOB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS chunk_vectors_model ON chunk_vectors(model);
+
+CREATE TRIGGER IF NOT EXISTS chunks_delete_vectors AFTER DELETE ON chunks BEGIN
+    DELETE FROM chunk_vectors WHERE chunk_id = old.id;
+END;
+
+CREATE TABLE IF NOT EXISTS document_signatures (
+  document_id   INTEGER PRIMARY KEY,
+  simhash       INTEGER NOT NULL
+);
+
+CREATE TRIGGER IF NOT EXISTS documents_delete_signatures AFTER DELETE ON documents BEGIN
+    DELETE FROM document_signatures WHERE document_id = old.id;
+END;
+
+-- Groups of documents that may be near-duplicates. A document joins the
+-- cluster of the most similar document stored before it, if any is at
+-- least DuplicateClusterThreshold similar, and starts a cluster otherwise.
+-- Search decides which members to hide by comparing each with the newest.
+CREATE TABLE IF NOT EXISTS document_clusters (
+  document_id   INTEGER PRIMARY KEY,
+  cluster_id    INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS document_clusters_cluster_id ON document_clusters(cluster_id);
+
+CREATE TRIGGER IF NOT EXISTS documents_delete_clusters AFTER DELETE ON documents BEGIN
+    DELETE FROM document_clusters WHERE document_id = old.id;
+END;
+
+CREATE TABLE IF NOT EXISTS document_hashes (
+  document_id   INTEGER PRIMARY KEY,
+  md5_checksum  TEXT NOT NULL
+);
+
+CREATE TRIGGER IF NOT EXISTS documents_delete_hashes AFTER DELETE ON documents BEGIN
+    DELETE FROM document_hashes WHERE document_id = old.id;
+END;
+
+CREATE TABLE IF NOT EXISTS document_versions (
+  id              INTEGER PRIMARY KEY,
+  drive_file_id   TEXT NOT NULL,
+  version         INT NOT NULL,
+  filepath        TEXT NOT NULL,
+  content         TEXT NOT NULL,
+  md5_checksum    TEXT NOT NULL,
+  last_modified   TEXT NOT NULL,
+  saved_at        TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (drive_file_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS version_revisions (
+  version_id    INTEGER PRIMARY KEY,
+  revision_id   TEXT NOT NULL,
+  author        TEXT NOT NULL
+);
+
+CREATE TRIGGER IF NOT EXISTS document_versions_delete_revisions AFTER DELETE ON document_versions BEGIN
+    DELETE FROM version_revisions WHERE version_id = old.id;
+END;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS versions_fts USING fts5(
+    filename,
+    path,
+    content
+);
+
+-- The filename is whatever follows the last slash of the path.
+CREATE TRIGGER IF NOT EXISTS document_versions_auto_insert AFTER INSERT ON document_versions BEGIN
+    INSERT INTO versions_fts(rowid, filename, path, content)
+    VALUES (new.id, substr(new.filepath, length(rtrim(new.filepath, replace(new.filepath, '/', ''))) + 1), new.filepath, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS document_versions_auto_delete AFTER DELETE ON document_versions BEGIN
+    DELETE FROM versions_fts WHERE rowid = old.id;
+END;
+
+-- Index versions stored before versions_fts existed.
+INSERT INTO versions_fts(rowid, filename, path, content)
+SELECT id, substr(filepath, length(rtrim(filepath, replace(filepath, '/', ''))) + 1), filepath, content
+FROM document_versions
+WHERE id > (SELECT COALESCE(MAX(rowid), 0) FROM versions_fts);
+
+CREATE TABLE IF NOT EXISTS comments (
+  id              INTEGER PRIMARY KEY,
+  document_id     INTEGER NOT NULL,
+  comment_id      TEXT NOT NULL,
+  thread_id       TEXT NOT NULL,
+  author          TEXT NOT NULL,
+  content         TEXT NOT NULL,
+  anchor          TEXT NOT NULL,
+  created_time    TEXT NOT NULL,
+  modified_time   TEXT NOT NULL,
+  resolved        BOOLEAN NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS comments_document_id ON comments(document_id);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS comments_fts USING fts5(
+    filename,
+    path,
+    content
+);
+
+CREATE TRIGGER IF NOT EXISTS comments_auto_insert AFTER INSERT ON comments BEGIN
+    INSERT INTO comments_fts(rowid, filename, path, content)
+    SELECT new.id, documents.filename, documents.filepath, new.content
+    FROM documents WHERE documents.id = new.document_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_auto_delete AFTER DELETE ON comments BEGIN
+    DELETE FROM comments_fts WHERE rowid = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS documents_delete_comments AFTER DELETE ON documents BEGIN
+    DELETE FROM comments WHERE document_id = old.id;
+END;
+
+CREATE TABLE IF NOT EXISTS document_metadata (
+  document_id           INTEGER PRIMARY KEY,
+  last_modifying_user   TEXT NOT NULL,
+  web_view_link         TEXT NOT NULL,
+  description           TEXT NOT NULL,
+  created_time          TEXT NOT NULL,
+  starred               BOOLEAN NOT NULL,
+  -- A JSON object of the file's appProperties.
+  app_properties        TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS document_owners (
+  document_id   INTEGER NOT NULL,
+  email         TEXT NOT NULL,
+  name          TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS document_owners_document_id ON document_owners(document_id);
+
+CREATE TRIGGER IF NOT EXISTS documents_delete_metadata AFTER DELETE ON documents BEGIN
+    DELETE FROM document_metadata WHERE document_id = old.id;
+    DELETE FROM document_owners WHERE document_id = old.id;
+END;
+
+-- Fields of the YAML front matter stripped from markdown documents, one
+-- row per value.
+CREATE TABLE IF NOT EXISTS front_matter (
+  document_id   INTEGER NOT NULL,
+  key           TEXT NOT NULL,
+  value         TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS front_matter_key_value ON front_matter(key, value);
+CREATE INDEX IF NOT EXISTS front_matter_document_id ON front_matter(document_id);
+
+CREATE TRIGGER IF NOT EXISTS documents_delete_front_matter AFTER DELETE ON documents BEGIN
+    DELETE FROM front_matter WHERE document_id = old.id;
+END;
+
+-- Who each document is shared with, as Drive reports it. A document with
+-- no rows here is readable by nobody when searching on someone's behalf.
+CREATE TABLE IF NOT EXISTS document_permissions (
+  document_id   INTEGER NOT NULL,
+  type          TEXT NOT NULL,
+  role          TEXT NOT NULL,
+  email         TEXT NOT NULL,
+  domain        TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS document_permissions_document_id ON document_permissions(document_id);
+
+CREATE TRIGGER IF NOT EXISTS documents_delete_permissions AFTER DELETE ON documents BEGIN
+    DELETE FROM document_permissions WHERE document_id = old.id;
+END;
+
+-- References each document makes to other documents and URLs. Targets are
+-- resolved when read, so a link to a document ingested later still finds
+-- it: by the file id of a Drive URL, by the absolute path of a relative
+-- link, or by the file name, with or without its extension, of a wiki link.
+CREATE TABLE IF NOT EXISTS document_links (
+  document_id           INTEGER NOT NULL,
+  kind                  TEXT NOT NULL,
+  target                TEXT NOT NULL,
+  text                  TEXT NOT NULL,
+  target_drive_file_id  TEXT NOT NULL,
+  target_path           TEXT NOT NULL,
+  target_name           TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS document_links_document_id ON document_links(document_id);
+CREATE INDEX IF NOT EXISTS document_links_target_drive_file_id ON document_links(target_drive_file_id);
+CREATE INDEX IF NOT EXISTS document_links_target_path ON document_links(target_path);
+CREATE INDEX IF NOT EXISTS document_links_target_name ON document_links(target_name);
+
+CREATE TRIGGER IF NOT EXISTS documents_delete_links AFTER DELETE ON documents BEGIN
+    DELETE FROM document_links WHERE document_id = old.id;
+END;
+
+-- Typed entities found in each document's content, such as ticket keys and
+-- email addresses, one row per distinct value.
+CREATE TABLE IF NOT EXISTS document_entities (
+  document_id   INTEGER NOT NULL,
+  type          TEXT NOT NULL COLLATE NOCASE,
+  value         TEXT NOT NULL COLLATE NOCASE,
+  mentions      INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS document_entities_type_value ON document_entities(type, value);
+CREATE INDEX IF NOT EXISTS document_entities_document_id ON document_entities(document_id);
+
+CREATE TRIGGER IF NOT EXISTS documents_delete_entities AFTER DELETE ON documents BEGIN
+    DELETE FROM document_entities WHERE document_id = old.id;
+END;
+
+-- The language detected in each document, for documents in a language
+-- that is stemmed.
+CREATE TABLE IF NOT EXISTS document_languages (
+  document_id   INTEGER NOT NULL,
+  language      TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS document_languages_document_id ON document_languages(document_id);
+CREATE INDEX IF NOT EXISTS document_languages_language ON document_languages(language);
+
+CREATE TRIGGER IF NOT EXISTS documents_delete_languages AFTER DELETE ON documents BEGIN
+    DELETE FROM document_languages WHERE document_id = old.id;
+END;
+
+-- The file name and content of those documents reduced to word stems in
+-- their language, so that a search for "deploying" also finds "deployed".
+-- The rowid is the document id. The path is kept as it is, since it names
+-- folders rather than saying anything. Rows are written by the
+-- application, which does the stemming.
+CREATE VIRTUAL TABLE IF NOT EXISTS documents_stemmed_fts USING fts5(
+    filename,
+    path,
+    content
+);
+
+CREATE TRIGGER IF NOT EXISTS documents_delete_stemmed AFTER DELETE ON documents BEGIN
+    DELETE FROM documents_stemmed_fts WHERE rowid = old.id;
+END;
+
+-- A summary of each document made of the sentences that best sum it up,
+-- picked when it is ingested.
+CREATE TABLE IF NOT EXISTS document_summaries (
+  document_id   INTEGER NOT NULL,
+  summary       TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS document_summaries_document_id ON document_summaries(document_id);
+
+CREATE TRIGGER IF NOT EXISTS documents_delete_summaries AFTER DELETE ON documents BEGIN
+    DELETE FROM document_summaries WHERE document_id = old.id;
+END;
+
+-- The terms that set each document apart from the rest of the corpus, by
+-- TF-IDF against every stored document. They are worked out again for the
+-- whole corpus after each ingest, so they follow the corpus as it grows.
+CREATE TABLE IF NOT EXISTS document_keywords (
+  document_id   INTEGER NOT NULL,
+  keyword       TEXT NOT NULL,
+  weight        REAL NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS document_keywords_document_id ON document_keywords(document_id);
+
+CREATE TRIGGER IF NOT EXISTS documents_delete_keywords AFTER DELETE ON documents BEGIN
+    DELETE FROM document_keywords WHERE document_id = old.id;
+END;
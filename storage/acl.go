@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"strings"
+)
+
+// Principal is someone a search runs on behalf of. Only documents shared
+// with them, with one of their groups, with their domain or with anyone
+// are returned.
+type Principal struct {
+	Email string
+	// Groups are the email addresses of every group the principal is in.
+	Groups []string
+}
+
+// readableClause restricts documents to those the principal can read. A
+// document whose permissions were never stored is not readable.
+func (p *Principal) readableClause() (string, []any) {
+	addresses := []any{strings.ToLower(p.Email)}
+	for _, group := range p.Groups {
+		addresses = append(addresses, strings.ToLower(group))
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(addresses)), ", ")
+
+	_, domain, _ := strings.Cut(strings.ToLower(p.Email), "@")
+
+	clause := `documents.id IN (SELECT document_permissions.document_id FROM document_permissions
+    WHERE document_permissions.type = 'anyone'
+       OR (document_permissions.type IN ('user', 'group') AND lower(document_permissions.email) IN (` + placeholders + `))
+       OR (document_permissions.type = 'domain' AND lower(document_permissions.domain) = ?))`
+
+	return clause, append(addresses, domain)
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pipeline "injestion-pipeline/db"
+	search "injestion-pipeline/query"
+)
+
+// asOfDocuments stands in for the documents table in an as-of search. It
+// holds, for each Drive file, the newest stored version modified at or
+// before the date, shaped like documents so the filters and sort orders
+// apply unchanged. Its id is the current document's, so results link to
+// what is stored today; version_id is the row in document_versions.
+const asOfDocuments = `WITH versions AS (
+  SELECT document_versions.*,
+         substr(filepath, length(rtrim(filepath, replace(filepath, '/', ''))) + 1) AS filename
+  FROM document_versions
+),
+documents AS (
+  SELECT COALESCE((SELECT current.id FROM main.documents current
+                   WHERE current.drive_file_id = versions.drive_file_id), 0) AS id,
+         versions.drive_file_id, versions.filename, versions.filepath, versions.content,
+         CASE WHEN instr(versions.filename, '.') = 0 THEN ''
+              ELSE lower(substr(versions.filename, length(rtrim(versions.filename, replace(versions.filename, '.', '')))))
+         END AS extension,
+         versions.last_modified,
+         length(CAST(versions.content AS BLOB)) AS size_bytes,
+         versions.id AS version_id,
+         versions.version,
+         COALESCE(version_revisions.author, '') AS author
+  FROM versions
+  LEFT JOIN version_revisions ON version_revisions.version_id = versions.id
+  WHERE versions.id = (
+    SELECT candidate.id FROM document_versions candidate
+    WHERE candidate.drive_file_id = versions.drive_file_id
+      AND julianday(candidate.last_modified) <= julianday(?)
+    ORDER BY julianday(candidate.last_modified) DESC, candidate.version DESC
+    LIMIT 1
+  )
+)`
+
+const (
+	asOfSelect = `SELECT ` + documentColumns + `, documents.version, documents.author,
+       -bm25(versions_fts, ?, ?, ?) AS score,
+       highlight(versions_fts, 0, char(2), char(3)),
+       highlight(versions_fts, 2, char(2), char(3))`
+	asOfFrom = `
+FROM versions_fts
+JOIN documents ON documents.version_id = versions_fts.rowid
+WHERE versions_fts MATCH ?`
+)
+
+// asOfSearch answers a keyword search from the stored versions that were
+// current at opts.AsOf instead of from the latest content. Only versions
+// kept by ingest can be searched, so documents first ingested after the
+// date, or whose older versions were pruned, may be missing.
+func (s *SQLiteDB) asOfSearch(ctx context.Context, q *search.Query, opts SearchOptions) (*SearchPage, error) {
+	if opts.Mode != ModeKeyword && opts.Mode != "" {
+		return nil, fmt.Errorf("searching as of a date only works in %s mode", ModeKeyword)
+	}
+
+	match := q.Match()
+	if match == "" {
+		return nil, fmt.Errorf("searching as of a date needs at least one search term")
+	}
+
+	var from strings.Builder
+	from.WriteString(asOfFrom)
+	args := []any{opts.AsOf.UTC().Format("2006-01-02 15:04:05.000"), match}
+
+	where, whereArgs := restrictions(q, false, opts)
+	from.WriteString(where)
+	args = append(args, whereArgs...)
+
+	page := &SearchPage{Offset: opts.Offset}
+
+	err := s.db.QueryRowContext(ctx, asOfDocuments+"\nSELECT COUNT(*)"+from.String(), args...).Scan(&page.Total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	pageQuery := asOfDocuments + "\n" + asOfSelect + from.String() + "\nORDER BY " + sortClauses[opts.Sort] + "\nLIMIT ? OFFSET ?"
+	pageArgs := append([]any{args[0], opts.Weights.Filename, opts.Weights.Path, opts.Weights.Content}, args[1:]...)
+	pageArgs = append(pageArgs, opts.Limit, opts.Offset)
+
+	rows, err := s.db.QueryContext(ctx, pageQuery, pageArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search versions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var doc pipeline.Document
+		var version VersionRef
+		var score float64
+		var filenameHighlight, contentHighlight string
+		if err := rows.Scan(
+			&doc.ID,
+			&doc.DriveFileID,
+			&doc.Filename,
+			&doc.Filepath,
+			&doc.Content,
+			&doc.Extension,
+			&doc.LastModified,
+			&doc.SizeBytes,
+			&version.Number,
+			&version.Author,
+			&score,
+			&filenameHighlight,
+			&contentHighlight,
+		); err != nil {
+			return nil, fmt.Errorf("failed to read search result: %w", err)
+		}
+
+		page.Results = append(page.Results, SearchResult{
+			Document:   doc,
+			Title:      parseHighlighted(filenameHighlight),
+			Snippets:   buildSnippets(contentHighlight, snippetMaxFragments),
+			Score:      score,
+			Retrievers: []string{RetrieverKeyword},
+			Version:    &version,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search versions: %w", err)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/models"
+	search "injestion-pipeline/query"
+)
+
+// commentMaxHits caps the matching comments shown under each document.
+const commentMaxHits = 3
+
+// commentHits ranks the matching comments once, so the documents they
+// belong to can be grouped, filtered and sorted like any other result.
+const commentHits = `WITH hits AS MATERIALIZED (
+  SELECT comments.document_id, -bm25(comments_fts, ?, ?, ?) AS score
+  FROM comments_fts
+  JOIN comments ON comments.id = comments_fts.rowid
+  WHERE comments_fts MATCH ?
+)`
+
+const (
+	commentSelect = `SELECT ` + documentColumns + `, MAX(hits.score) AS score`
+	commentFrom   = `
+FROM hits
+JOIN documents ON documents.id = hits.document_id
+WHERE 1 = 1`
+)
+
+const commentHitsQuery = `SELECT comments.document_id, comments.comment_id, comments.thread_id, comments.author,
+       comments.anchor, comments.created_time, comments.resolved,
+       highlight(comments_fts, 2, char(2), char(3))
+FROM comments_fts
+JOIN comments ON comments.id = comments_fts.rowid
+WHERE comments_fts MATCH ?
+  AND comments.document_id IN (%s)
+ORDER BY bm25(comments_fts, ?, ?, ?)`
+
+// CommentHit is a comment that matched a search, shown under the document
+// it was made on.
+type CommentHit struct {
+	ID       string
+	ThreadID string
+	Author   string
+	// Anchor is the document text the thread was made on, if any.
+	Anchor   string
+	Created  string
+	Resolved bool
+	Snippet  Fragment
+}
+
+// saveComments stores the comment threads of a newly saved document. When
+// they were not fetched this time, the ones kept from the copy it replaced
+// are stored again instead.
+func saveComments(ctx context.Context, queries *pipeline.Queries, documentID int64, comments []models.Comment, kept []pipeline.Comment) error {
+	if comments == nil {
+		for _, c := range kept {
+			comments = append(comments, models.Comment{
+				ID:           c.CommentID,
+				ThreadID:     c.ThreadID,
+				Author:       c.Author,
+				Content:      c.Content,
+				Anchor:       c.Anchor,
+				CreatedTime:  c.CreatedTime,
+				ModifiedTime: c.ModifiedTime,
+				Resolved:     c.Resolved,
+			})
+		}
+	}
+
+	for _, c := range comments {
+		err := queries.CreateComment(ctx, pipeline.CreateCommentParams{
+			DocumentID:   documentID,
+			CommentID:    c.ID,
+			ThreadID:     c.ThreadID,
+			Author:       c.Author,
+			Content:      c.Content,
+			Anchor:       c.Anchor,
+			CreatedTime:  c.CreatedTime,
+			ModifiedTime: c.ModifiedTime,
+			Resolved:     c.Resolved,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save comment %s: %w", c.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// commentSearch matches the query against comments and replies instead of
+// document text. Each result is the document a matching comment was made
+// on, scored by its best comment, with the matching comments attached.
+func (s *SQLiteDB) commentSearch(ctx context.Context, q *search.Query, opts SearchOptions) (*SearchPage, error) {
+	if opts.Mode != ModeKeyword && opts.Mode != "" {
+		return nil, fmt.Errorf("searching comments only works in %s mode", ModeKeyword)
+	}
+
+	// comments_fts carries the file name and path of the document each
+	// comment is on, so that title: and path: terms can narrow a comment
+	// search; other terms must be in the comment itself.
+	match := q.MatchIn("content")
+	if match == "" {
+		return nil, fmt.Errorf("searching comments needs at least one search term")
+	}
+
+	var from strings.Builder
+	from.WriteString(commentFrom)
+	args := []any{opts.Weights.Filename, opts.Weights.Path, opts.Weights.Content, match}
+
+	where, whereArgs := restrictions(q, false, opts)
+	from.WriteString(where)
+	args = append(args, whereArgs...)
+
+	page := &SearchPage{Offset: opts.Offset}
+
+	err := s.db.QueryRowContext(ctx, commentHits+"\nSELECT COUNT(DISTINCT documents.id)"+from.String(), args...).Scan(&page.Total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	pageQuery := commentHits + "\n" + commentSelect + from.String() +
+		"\nGROUP BY documents.id\nORDER BY " + sortClauses[opts.Sort] + "\nLIMIT ? OFFSET ?"
+	rows, err := s.db.QueryContext(ctx, pageQuery, append(args, opts.Limit, opts.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search comments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var doc pipeline.Document
+		var score float64
+		if err := rows.Scan(
+			&doc.ID,
+			&doc.DriveFileID,
+			&doc.Filename,
+			&doc.Filepath,
+			&doc.Content,
+			&doc.Extension,
+			&doc.LastModified,
+			&doc.SizeBytes,
+			&score,
+		); err != nil {
+			return nil, fmt.Errorf("failed to read search result: %w", err)
+		}
+
+		page.Results = append(page.Results, SearchResult{
+			Document:   doc,
+			Title:      Fragment{Text: doc.Filename},
+			Score:      score,
+			Retrievers: []string{RetrieverKeyword},
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search comments: %w", err)
+	}
+
+	if len(page.Results) > 0 {
+		if err := s.attachComments(ctx, page.Results, match, opts.Weights); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// attachComments lists under each result its best-ranked matching
+// comments, up to commentMaxHits.
+func (s *SQLiteDB) attachComments(ctx context.Context, results []SearchResult, match string, weights FieldWeights) error {
+	args := []any{match}
+	for _, r := range results {
+		args = append(args, r.Document.ID)
+	}
+	args = append(args, weights.Filename, weights.Path, weights.Content)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(results)), ", ")
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(commentHitsQuery, placeholders), args...)
+	if err != nil {
+		return fmt.Errorf("failed to rank comments: %w", err)
+	}
+	defer rows.Close()
+
+	index := make(map[int64]int, len(results))
+	for i, r := range results {
+		index[r.Document.ID] = i
+	}
+
+	for rows.Next() {
+		var documentID int64
+		var hit CommentHit
+		var highlighted string
+		if err := rows.Scan(&documentID, &hit.ID, &hit.ThreadID, &hit.Author, &hit.Anchor, &hit.Created, &hit.Resolved, &highlighted); err != nil {
+			return fmt.Errorf("failed to read comment: %w", err)
+		}
+
+		i, ok := index[documentID]
+		if !ok || len(results[i].Comments) >= commentMaxHits {
+			continue
+		}
+
+		if fragments := buildSnippets(highlighted, 1); len(fragments) > 0 {
+			hit.Snippet = fragments[0]
+		}
+		results[i].Comments = append(results[i].Comments, hit)
+	}
+
+	return rows.Err()
+}
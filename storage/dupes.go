@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/dedupe"
+)
+
+// DefaultDuplicateThreshold is the SimHash similarity at which two
+// documents count as near-duplicates. Unrelated texts rarely share more
+// than 70% of their bits, while a copy with a few edited lines keeps well
+// over 90%.
+const DefaultDuplicateThreshold = 0.9
+
+// DuplicateClusterThreshold is the similarity at which a saved document
+// joins the cluster of an earlier one. It is the lowest threshold
+// duplicates can be looked for at, since documents in different clusters
+// are never compared.
+const DuplicateClusterThreshold = 0.8
+
+// collapsedQuery hides every member of a cluster that is at least ? similar
+// to the cluster's newest document, which is the copy search keeps. The
+// candidates for newest are those matching the %s condition.
+const collapsedQuery = `documents.id NOT IN (
+    SELECT member.document_id FROM document_clusters AS member
+    JOIN document_signatures AS member_signature ON member_signature.document_id = member.document_id
+    JOIN (
+        SELECT document_clusters.cluster_id, document_clusters.document_id, document_signatures.simhash,
+               ROW_NUMBER() OVER (PARTITION BY document_clusters.cluster_id
+                                  ORDER BY julianday(documents.last_modified) DESC, documents.id DESC) AS position
+        FROM document_clusters
+        JOIN documents ON documents.id = document_clusters.document_id
+        JOIN document_signatures ON document_signatures.document_id = document_clusters.document_id
+        WHERE %s
+    ) AS kept ON kept.cluster_id = member.cluster_id AND kept.position = 1
+    WHERE member.document_id != kept.document_id
+      AND simhash_similarity(member_signature.simhash, kept.simhash) >= ?)`
+
+type DuplicateMember struct {
+	DocumentID int64
+	Path       string
+	Modified   string
+	SizeBytes  int64
+	// Similarity is the member's SimHash similarity to the newest member.
+	Similarity float64
+}
+
+// DuplicateCluster is a group of near-duplicate documents, newest first.
+type DuplicateCluster struct {
+	Members []DuplicateMember
+}
+
+// NearDuplicates returns the documents that are at least threshold similar
+// to the newest document of their cluster, grouped by cluster. Largest
+// clusters come first.
+func (s *SQLiteDB) NearDuplicates(ctx context.Context, threshold float64) ([]DuplicateCluster, error) {
+	if threshold <= 0 {
+		threshold = DefaultDuplicateThreshold
+	}
+	if threshold < DuplicateClusterThreshold {
+		return nil, fmt.Errorf("duplicate threshold %.2f is below %.2f, the similarity documents are clustered at", threshold, DuplicateClusterThreshold)
+	}
+
+	rows, err := s.queries.ListDocumentSignatures(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signatures: %w", err)
+	}
+
+	var clusters []DuplicateCluster
+	for start := 0; start < len(rows); {
+		end := start + 1
+		for end < len(rows) && rows[end].ClusterID == rows[start].ClusterID {
+			end++
+		}
+		group := rows[start:end]
+		start = end
+
+		sort.Slice(group, func(i, j int) bool {
+			a, b := group[i], group[j]
+			if a.LastModified != b.LastModified {
+				return a.LastModified > b.LastModified
+			}
+			return a.DocumentID > b.DocumentID
+		})
+
+		newest := uint64(group[0].Simhash)
+		cluster := DuplicateCluster{}
+		for _, row := range group {
+			similarity := dedupe.Similarity(newest, uint64(row.Simhash))
+			if similarity < threshold {
+				continue
+			}
+			cluster.Members = append(cluster.Members, DuplicateMember{
+				DocumentID: row.DocumentID,
+				Path:       row.Filepath,
+				Modified:   row.LastModified,
+				SizeBytes:  row.SizeBytes,
+				Similarity: similarity,
+			})
+		}
+		if len(cluster.Members) > 1 {
+			clusters = append(clusters, cluster)
+		}
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return len(clusters[i].Members) > len(clusters[j].Members)
+	})
+
+	return clusters, nil
+}
+
+// collapsedClause compiles the condition that leaves out near-duplicates of
+// a newer document. With a principal, the copy kept is the newest one they
+// can read, so a newer copy they cannot see does not hide the rest.
+func collapsedClause(threshold float64, principal *Principal) (string, []any) {
+	if principal == nil {
+		return fmt.Sprintf(collapsedQuery, "1"), []any{threshold}
+	}
+	readable, args := principal.readableClause()
+	return fmt.Sprintf(collapsedQuery, readable), append(args, threshold)
+}
+
+// assignCluster puts a newly signed document in the cluster of the most
+// similar document stored so far, or in a cluster of its own.
+func assignCluster(ctx context.Context, queries *pipeline.Queries, documentID int64, simhash int64) error {
+	clusterID, err := queries.ClosestCluster(ctx, pipeline.ClosestClusterParams{
+		Simhash:   simhash,
+		Threshold: DuplicateClusterThreshold,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		clusterID, err = queries.NextClusterID(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find duplicate cluster: %w", err)
+	}
+
+	err = queries.CreateDocumentCluster(ctx, pipeline.CreateDocumentClusterParams{
+		DocumentID: documentID,
+		ClusterID:  clusterID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save duplicate cluster: %w", err)
+	}
+	return nil
+}
+
+// clusterDocuments signs and clusters the documents stored before
+// signatures or clusters were kept, oldest first, as if they had been saved
+// in that order. Documents too short to sign are left unsigned.
+func clusterDocuments(ctx context.Context, _ *sql.Tx, queries *pipeline.Queries) error {
+	unsigned, err := queries.ListUnsignedDocuments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to find unsigned documents: %w", err)
+	}
+	for _, doc := range unsigned {
+		if !dedupe.Signable(doc.Content) {
+			continue
+		}
+		err := queries.CreateDocumentSignature(ctx, pipeline.CreateDocumentSignatureParams{
+			DocumentID: doc.ID,
+			Simhash:    int64(dedupe.SimHash(doc.Content)),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to sign document %d: %w", doc.ID, err)
+		}
+	}
+
+	unclustered, err := queries.ListUnclusteredSignatures(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to find unclustered documents: %w", err)
+	}
+	for _, sig := range unclustered {
+		if err := assignCluster(ctx, queries, sig.DocumentID, sig.Simhash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simhashSimilarity backs the simhash_similarity SQL function.
+func simhashSimilarity(a, b int64) float64 {
+	return dedupe.Similarity(uint64(a), uint64(b))
+}
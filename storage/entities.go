@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/models"
+)
+
+// saveEntities stores the entities found in a newly saved document.
+func saveEntities(ctx context.Context, queries *pipeline.Queries, documentID int64, entities []models.Entity) error {
+	for _, entity := range entities {
+		err := queries.CreateDocumentEntity(ctx, pipeline.CreateDocumentEntityParams{
+			DocumentID: documentID,
+			Type:       entity.Type,
+			Value:      entity.Value,
+			Mentions:   int64(entity.Mentions),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save %s entity %s: %w", entity.Type, entity.Value, err)
+		}
+	}
+	return nil
+}
+
+// TopEntities lists the entities found in the most documents, then with
+// the most mentions, up to limit. An empty entityType lists every type.
+func (s *SQLiteDB) TopEntities(ctx context.Context, entityType string, limit int) ([]pipeline.ListTopEntitiesRow, error) {
+	rows, err := s.queries.ListTopEntities(ctx, pipeline.ListTopEntitiesParams{
+		Type:  entityType,
+		Limit: int64(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list entities: %w", err)
+	}
+	return rows, nil
+}
+
+// entityClause matches documents naming one of values as an entity of the
+// given type, or of any type when entityType is empty. Types and values
+// compare without regard to case.
+func entityClause(entityType string, values []string) (string, []any) {
+	var args []any
+	clause := "documents.id IN (SELECT document_entities.document_id FROM document_entities WHERE "
+	if entityType != "" {
+		clause += "document_entities.type = ? AND "
+		args = append(args, entityType)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	clause += "document_entities.value IN (" + placeholders + "))"
+	for _, v := range values {
+		args = append(args, v)
+	}
+	return clause, args
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/models"
+)
+
+// saveFrontMatter stores the front matter fields of a newly saved document.
+func saveFrontMatter(ctx context.Context, queries *pipeline.Queries, documentID int64, fields []models.Field) error {
+	for _, field := range fields {
+		err := queries.CreateFrontMatterField(ctx, pipeline.CreateFrontMatterFieldParams{
+			DocumentID: documentID,
+			Key:        field.Key,
+			Value:      field.Value,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save front matter field %s: %w", field.Key, err)
+		}
+	}
+	return nil
+}
+
+// DocumentFrontMatter returns the front matter fields stored for a
+// document, in the order they were written.
+func (s *SQLiteDB) DocumentFrontMatter(ctx context.Context, documentID int64) ([]models.Field, error) {
+	rows, err := s.queries.ListFrontMatter(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load front matter: %w", err)
+	}
+
+	var fields []models.Field
+	for _, row := range rows {
+		fields = append(fields, models.Field{Key: row.Key, Value: row.Value})
+	}
+	return fields, nil
+}
+
+// frontMatterClause matches documents with a front matter field named key,
+// or its plural, whose value is one of values. The plural lets tag:hr find
+// a "tags: [hr, policy]" list.
+func frontMatterClause(key string, values []string) (string, []any) {
+	args := []any{key, key + "s"}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	for _, v := range values {
+		args = append(args, v)
+	}
+
+	clause := "documents.id IN (SELECT front_matter.document_id FROM front_matter " +
+		"WHERE front_matter.key IN (?, ?) AND lower(front_matter.value) IN (" + placeholders + "))"
+	return clause, args
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	search "injestion-pipeline/query"
+)
+
+// hybridDepth is how many results each retriever contributes to the fusion
+// at least. Deeper lists find more agreement between the retrievers at the
+// cost of ranking more candidates.
+const hybridDepth = 100
+
+// FusionWeights tunes reciprocal rank fusion. Each retriever adds
+// weight / (K + rank) to the score of every document it returned, so a
+// larger K flattens the advantage of the top ranks.
+type FusionWeights struct {
+	Keyword  float64
+	Semantic float64
+	K        float64
+}
+
+var DefaultFusionWeights = FusionWeights{
+	Keyword:  1.0,
+	Semantic: 1.0,
+	K:        60,
+}
+
+// hybridSearch runs the keyword and semantic retrievers and merges their
+// rankings with reciprocal rank fusion. Total counts the distinct
+// documents either retriever returned within hybridDepth.
+func (s *SQLiteDB) hybridSearch(ctx context.Context, q *search.Query, opts SearchOptions) (*SearchPage, error) {
+	depth := SearchOptions{
+		Limit:     max(opts.Offset+opts.Limit, hybridDepth),
+		Sort:      SortRelevance,
+		Weights:   opts.Weights,
+		Embedder:  opts.Embedder,
+		Principal: opts.Principal,
+
+		CollapseDuplicates: opts.CollapseDuplicates,
+		DuplicateThreshold: opts.DuplicateThreshold,
+	}
+
+	keyword, err := s.keywordSearch(ctx, q, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := []rankedList{{retriever: RetrieverKeyword, weight: opts.Fusion.Keyword, results: keyword.Results}}
+
+	if q.Text() != "" {
+		semantic, err := s.semanticSearch(ctx, q, depth)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, rankedList{retriever: RetrieverSemantic, weight: opts.Fusion.Semantic, results: semantic.Results})
+	}
+
+	fused := fuseReciprocalRank(lists, opts.Fusion.K)
+	if opts.Sort != SortRelevance {
+		sortResults(fused, opts.Sort)
+	}
+
+	page := &SearchPage{Total: len(fused), Offset: opts.Offset}
+	if opts.Offset < len(fused) {
+		page.Results = fused[opts.Offset:min(opts.Offset+opts.Limit, len(fused))]
+	}
+
+	return page, nil
+}
+
+type rankedList struct {
+	retriever string
+	weight    float64
+	results   []SearchResult
+}
+
+// fuseReciprocalRank merges ranked lists into one, ordered by fused score.
+// A document found by several retrievers keeps the first list's snippets,
+// which are the keyword highlights when keyword search found it.
+func fuseReciprocalRank(lists []rankedList, k float64) []SearchResult {
+	byID := make(map[int64]*SearchResult)
+	var order []int64
+
+	for _, list := range lists {
+		for rank, result := range list.results {
+			contribution := list.weight / (k + float64(rank+1))
+
+			fused, ok := byID[result.Document.ID]
+			if !ok {
+				result.Score = 0
+				result.Retrievers = nil
+				fused = &result
+				byID[result.Document.ID] = fused
+				order = append(order, result.Document.ID)
+			}
+			if fused.Section == nil {
+				fused.Section = result.Section
+			}
+
+			fused.Score += contribution
+			fused.Retrievers = append(fused.Retrievers, list.retriever)
+		}
+	}
+
+	results := make([]SearchResult, 0, len(order))
+	for _, id := range order {
+		results = append(results, *byID[id])
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results
+}
+
+// sortResults orders loaded results by a document field, matching the
+// ORDER BY clauses of keyword search.
+func sortResults(results []SearchResult, order SortOrder) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i].Document, results[j].Document
+		switch order {
+		case SortModified:
+			if a.LastModified != b.LastModified {
+				return a.LastModified > b.LastModified
+			}
+		case SortPath:
+			if a.Filepath != b.Filepath {
+				return a.Filepath < b.Filepath
+			}
+		case SortSize:
+			if a.SizeBytes != b.SizeBytes {
+				return a.SizeBytes > b.SizeBytes
+			}
+		}
+		return a.ID < b.ID
+	})
+}
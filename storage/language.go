@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/language"
+	"injestion-pipeline/models"
+	search "injestion-pipeline/query"
+)
+
+const insertStemmed = `INSERT INTO documents_stemmed_fts (rowid, filename, path, content)
+VALUES (?, ?, ?, ?)`
+
+// stemmedScale scales the BM25 score of documents found only by their
+// stems. Scores from the two indexes are not strictly comparable, and a
+// document containing the words as typed should rank above one that only
+// shares their stems.
+const stemmedScale = 0.5
+
+// stemmedArm finds the documents in one language that match the query by
+// stem but not as typed. The exact-match arm supplies the highlights, so
+// this one leaves them NULL and the document text is shown as it is.
+const stemmedArm = `
+  UNION ALL
+  SELECT documents_stemmed_fts.rowid, -bm25(documents_stemmed_fts, ?, ?, ?) * ?, NULL, NULL
+  FROM documents_stemmed_fts
+  JOIN document_languages ON document_languages.document_id = documents_stemmed_fts.rowid
+  WHERE documents_stemmed_fts MATCH ?
+    AND document_languages.language = ?
+    AND documents_stemmed_fts.rowid NOT IN (SELECT rowid FROM documents_fts WHERE documents_fts MATCH ?)`
+
+// saveLanguage records the language of a newly saved document and indexes
+// its stemmed text. Documents in no stemmed language are left out of the
+// stemmed index, and only match as typed.
+func saveLanguage(ctx context.Context, tx *sql.Tx, queries *pipeline.Queries, documentID int64, doc *models.Document) error {
+	if !language.Supported(doc.Language) {
+		return nil
+	}
+
+	err := queries.CreateDocumentLanguage(ctx, pipeline.CreateDocumentLanguageParams{
+		DocumentID: documentID,
+		Language:   doc.Language,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save document language: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, insertStemmed,
+		documentID,
+		language.StemText(doc.Language, doc.FileName),
+		doc.FilePath,
+		language.StemText(doc.Language, doc.Content),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to index stemmed text: %w", err)
+	}
+	return nil
+}
+
+// DocumentLanguage returns the language detected in a document, or an
+// empty string when it is not one that is stemmed.
+func (s *SQLiteDB) DocumentLanguage(ctx context.Context, documentID int64) (string, error) {
+	lang, err := s.queries.GetDocumentLanguage(ctx, documentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load document language: %w", err)
+	}
+	return lang, nil
+}
+
+// stemmedArms builds the stemmedArm for each language a keyword search
+// could find documents in: those of the query's lang: filters, or every
+// stemmed language when it has none. Each arm matches the query's terms
+// stemmed in its language.
+func stemmedArms(q *search.Query, match string, weights FieldWeights) (string, []any) {
+	var b strings.Builder
+	var args []any
+
+	for _, lang := range searchLanguages(q) {
+		stemmed := q.StemmedMatch(func(text string) string {
+			return language.StemText(lang, text)
+		})
+		b.WriteString(stemmedArm)
+		args = append(args, weights.Filename, weights.Path, weights.Content, stemmedScale, stemmed, lang, match)
+	}
+	return b.String(), args
+}
+
+// searchLanguages returns the languages named by the query's lang:
+// filters, or all of them when there are none.
+func searchLanguages(q *search.Query) []string {
+	var langs []string
+	seen := make(map[string]bool)
+	for _, f := range q.Filters {
+		if f.Field != "lang" || f.Negate {
+			continue
+		}
+		for _, lang := range f.Values {
+			if !seen[lang] {
+				seen[lang] = true
+				langs = append(langs, lang)
+			}
+		}
+	}
+	if len(langs) == 0 {
+		return language.Languages
+	}
+	return langs
+}
+
+// languageClause matches documents detected to be in one of langs.
+func languageClause(langs []string) (string, []any) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(langs)), ", ")
+	clause := "documents.id IN (SELECT document_languages.document_id FROM document_languages WHERE document_languages.language IN (" + placeholders + "))"
+
+	args := make([]any, 0, len(langs))
+	for _, lang := range langs {
+		args = append(args, lang)
+	}
+	return clause, args
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/models"
+)
+
+// saveLinks stores the links of a newly saved document.
+func saveLinks(ctx context.Context, queries *pipeline.Queries, documentID int64, links []models.Link) error {
+	for _, link := range links {
+		err := queries.CreateDocumentLink(ctx, pipeline.CreateDocumentLinkParams{
+			DocumentID:        documentID,
+			Kind:              link.Kind,
+			Target:            link.Target,
+			Text:              link.Text,
+			TargetDriveFileID: link.DriveFileID,
+			TargetPath:        link.Path,
+			TargetName:        link.Name,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save link to %s: %w", link.Target, err)
+		}
+	}
+	return nil
+}
+
+// OutboundLinks lists the links a document makes in the order they appear,
+// each with the stored document it resolves to. TargetID is 0 for links
+// to URLs and to documents that are not stored; a wiki link whose name
+// matches several documents is listed once for each.
+func (s *SQLiteDB) OutboundLinks(ctx context.Context, documentID int64) ([]pipeline.ListDocumentLinksRow, error) {
+	links, err := s.queries.ListDocumentLinks(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+	return links, nil
+}
+
+// Backlinks lists the links other documents make to a document, by the
+// path of the document they are in.
+func (s *SQLiteDB) Backlinks(ctx context.Context, documentID int64) ([]pipeline.ListBacklinksRow, error) {
+	links, err := s.queries.ListBacklinks(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backlinks: %w", err)
+	}
+	return links, nil
+}
+
+// OrphanDocuments lists the documents no other document links to, by path.
+func (s *SQLiteDB) OrphanDocuments(ctx context.Context) ([]pipeline.Document, error) {
+	docs, err := s.queries.ListOrphanDocuments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list orphan documents: %w", err)
+	}
+	return docs, nil
+}
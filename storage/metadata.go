@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/models"
+)
+
+// saveMetadata stores meta for a document, replacing what was stored for
+// it before. Documents that did not come from Drive have none.
+func saveMetadata(ctx context.Context, queries *pipeline.Queries, documentID int64, meta *models.Metadata) error {
+	if meta == nil {
+		return nil
+	}
+
+	appProperties, err := json.Marshal(meta.AppProperties)
+	if err != nil {
+		return fmt.Errorf("failed to encode app properties: %w", err)
+	}
+
+	err = queries.UpsertDocumentMetadata(ctx, pipeline.UpsertDocumentMetadataParams{
+		DocumentID:        documentID,
+		LastModifyingUser: meta.LastModifyingUser,
+		WebViewLink:       meta.WebViewLink,
+		Description:       meta.Description,
+		CreatedTime:       meta.CreatedTime,
+		Starred:           meta.Starred,
+		AppProperties:     string(appProperties),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save metadata: %w", err)
+	}
+
+	if err := queries.DeleteDocumentOwners(ctx, documentID); err != nil {
+		return fmt.Errorf("failed to replace owners: %w", err)
+	}
+	for _, owner := range meta.Owners {
+		err := queries.CreateDocumentOwner(ctx, pipeline.CreateDocumentOwnerParams{
+			DocumentID: documentID,
+			Email:      owner.Email,
+			Name:       owner.Name,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save owner %s: %w", owner.Email, err)
+		}
+	}
+
+	if err := queries.DeleteDocumentPermissions(ctx, documentID); err != nil {
+		return fmt.Errorf("failed to replace permissions: %w", err)
+	}
+	for _, perm := range meta.Permissions {
+		err := queries.CreateDocumentPermission(ctx, pipeline.CreateDocumentPermissionParams{
+			DocumentID: documentID,
+			Type:       perm.Type,
+			Role:       perm.Role,
+			Email:      perm.Email,
+			Domain:     perm.Domain,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save permission: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateMetadata replaces the metadata of the document stored for a Drive
+// file without touching its content, for files a crawl skipped because
+// they had not changed.
+func (s *SQLiteDB) UpdateMetadata(ctx context.Context, driveFileID string, meta *models.Metadata) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := s.queries.WithTx(tx)
+
+	doc, err := queries.GetDocumentByDriveFileID(ctx, driveFileID)
+	if err != nil {
+		return fmt.Errorf("failed to find document for %s: %w", driveFileID, err)
+	}
+	if err := saveMetadata(ctx, queries, doc.ID, meta); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit metadata: %w", err)
+	}
+	return nil
+}
+
+// DocumentMetadata returns the Drive metadata stored for a document, or
+// nil when there is none.
+func (s *SQLiteDB) DocumentMetadata(ctx context.Context, documentID int64) (*models.Metadata, error) {
+	row, err := s.queries.GetDocumentMetadata(ctx, documentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	meta := &models.Metadata{
+		LastModifyingUser: row.LastModifyingUser,
+		WebViewLink:       row.WebViewLink,
+		Description:       row.Description,
+		CreatedTime:       row.CreatedTime,
+		Starred:           row.Starred,
+	}
+	if err := json.Unmarshal([]byte(row.AppProperties), &meta.AppProperties); err != nil {
+		return nil, fmt.Errorf("failed to decode app properties: %w", err)
+	}
+
+	owners, err := s.queries.ListDocumentOwners(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load owners: %w", err)
+	}
+	for _, owner := range owners {
+		meta.Owners = append(meta.Owners, models.Person{Name: owner.Name, Email: owner.Email})
+	}
+
+	return meta, nil
+}
+
+// attachMetadata loads the Drive metadata and front matter of every result.
+func (s *SQLiteDB) attachMetadata(ctx context.Context, results []SearchResult) error {
+	for i := range results {
+		meta, err := s.DocumentMetadata(ctx, results[i].Document.ID)
+		if err != nil {
+			return err
+		}
+		results[i].Metadata = meta
+
+		results[i].FrontMatter, err = s.DocumentFrontMatter(ctx, results[i].Document.ID)
+		if err != nil {
+			return err
+		}
+
+		results[i].Language, err = s.DocumentLanguage(ctx, results[i].Document.ID)
+		if err != nil {
+			return err
+		}
+
+		results[i].Summary, results[i].Keywords, err = s.DocumentSummary(ctx, results[i].Document.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// likePrefix escapes s for a LIKE pattern matching strings that start
+// with it.
+func likePrefix(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s) + "%"
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"injestion-pipeline/chunking"
+	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/language"
+	"injestion-pipeline/models"
+	"injestion-pipeline/summary"
+)
+
+// migration brings a database made by an earlier version of the schema up
+// to date. schema.sql only creates what is missing, so a table or trigger
+// whose definition changed has to be dropped first and, if it held data,
+// filled again once the schema has recreated it. Data that takes more than
+// SQL to derive is filled by fillFunc.
+type migration struct {
+	drop     string
+	fill     string
+	fillFunc func(context.Context, *sql.Tx, *pipeline.Queries) error
+}
+
+// migrations[i] moves a database from schema version i, as kept in
+// PRAGMA user_version, to i+1.
+var migrations = []migration{
+	// documents_fts gained the path column, which its triggers fill.
+	{
+		drop: `DROP TRIGGER IF EXISTS documents_auto_insert;
+DROP TRIGGER IF EXISTS documents_auto_delete;
+DROP TRIGGER IF EXISTS documents_auto_update;
+DROP TABLE IF EXISTS documents_vocab;
+DROP TABLE IF EXISTS documents_vocab_rows;
+DROP TABLE IF EXISTS documents_fts;`,
+		fill: `INSERT INTO documents_fts(rowid, filename, path, content)
+SELECT id, filename, filepath, content FROM documents;`,
+	},
+	// Near-duplicate clusters are kept at ingest rather than worked out on
+	// every search.
+	{
+		fillFunc: clusterDocuments,
+	},
+	// Chunks, languages with the stemmed index, summaries and keywords are
+	// worked out at ingest, so documents stored before each was kept have
+	// none until they are fetched again.
+	{
+		fillFunc: backfillDocuments,
+	},
+	// Documents too short to sign were signed anyway, and all the empty
+	// ones ended up in one cluster. They are signed and clustered again.
+	{
+		fill: `DELETE FROM document_clusters;
+DELETE FROM document_signatures;`,
+		fillFunc: clusterDocuments,
+	},
+}
+
+// schemaVersion is the version schema.sql creates.
+var schemaVersion = len(migrations)
+
+// applySchema runs schema.sql, migrating the database first if an earlier
+// version made it. A new database is simply created at schemaVersion.
+func applySchema(db *sql.DB, schema string) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version > schemaVersion {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", version, schemaVersion)
+	}
+
+	var existing int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'documents'").Scan(&existing)
+	if err != nil {
+		return fmt.Errorf("failed to inspect database: %w", err)
+	}
+	if existing == 0 {
+		version = schemaVersion
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	pending := migrations[version:]
+	for i, m := range pending {
+		if m.drop == "" {
+			continue
+		}
+		if _, err := tx.Exec(m.drop); err != nil {
+			return fmt.Errorf("failed to migrate schema to version %d: %w", version+i+1, err)
+		}
+	}
+
+	if _, err := tx.Exec(schema); err != nil {
+		return err
+	}
+
+	queries := pipeline.New(tx)
+	for i, m := range pending {
+		if m.fill != "" {
+			if _, err := tx.Exec(m.fill); err != nil {
+				return fmt.Errorf("failed to migrate schema to version %d: %w", version+i+1, err)
+			}
+		}
+		if m.fillFunc != nil {
+			if err := m.fillFunc(context.Background(), tx, queries); err != nil {
+				return fmt.Errorf("failed to migrate schema to version %d: %w", version+i+1, err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion)); err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	return tx.Commit()
+}
+
+// backfillDocuments derives what ingest would have stored alongside each
+// document from the content already kept. Chunks are stored without
+// vectors, which need an embedder; ingest --refetch adds them. Content
+// hashes are not derived either: they are of the file as Drive serves it,
+// and a document without one is simply downloaded again on the next
+// ingest.
+func backfillDocuments(ctx context.Context, tx *sql.Tx, queries *pipeline.Queries) error {
+	unchunked, err := queries.ListUnchunkedDocuments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to find documents without chunks: %w", err)
+	}
+	for _, doc := range unchunked {
+		for _, chunk := range chunking.Split(doc.Content, doc.Extension, chunking.DefaultOptions) {
+			_, err := queries.CreateChunk(ctx, pipeline.CreateChunkParams{
+				DocumentID: doc.ID,
+				Ordinal:    int64(chunk.Ordinal),
+				Heading:    chunk.Heading,
+				Content:    chunk.Content,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to save chunk %d of document %d: %w", chunk.Ordinal, doc.ID, err)
+			}
+		}
+	}
+
+	undetected, err := queries.ListDocumentsWithoutLanguage(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to find documents without a language: %w", err)
+	}
+	for _, doc := range undetected {
+		err := saveLanguage(ctx, tx, queries, doc.ID, &models.Document{
+			FileName: doc.Filename,
+			FilePath: doc.Filepath,
+			Content:  doc.Content,
+			Language: language.Detect(doc.Content),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	unsummarized, err := queries.ListDocumentsWithoutSummary(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to find documents without a summary: %w", err)
+	}
+	for _, doc := range unsummarized {
+		err := saveSummary(ctx, queries, doc.ID, &models.Document{
+			Summary: summary.Summarize(doc.Content, summary.DefaultSentences),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return refreshKeywords(ctx, tx, queries)
+}
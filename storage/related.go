@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/embedding"
+	"injestion-pipeline/language"
+	search "injestion-pipeline/query"
+)
+
+// DefaultRelatedTerms is how many distinctive terms of a document are
+// searched for when looking for related documents.
+const DefaultRelatedTerms = 20
+
+// documentTermsQuery counts the occurrences of each term of one document in
+// its filename and content, along with the number of documents the term
+// appears in. fts5vocab cannot look up a document directly, so this scans
+// the instance table; it is fine for the corpus sizes this tool targets.
+const documentTermsQuery = `SELECT documents_vocab.term, COUNT(*), documents_vocab_rows.doc
+FROM documents_vocab
+JOIN documents_vocab_rows ON documents_vocab_rows.term = documents_vocab.term
+WHERE documents_vocab.doc = ?
+  AND documents_vocab.col IN ('filename', 'content')
+GROUP BY documents_vocab.term`
+
+type RelatedOptions struct {
+	Limit int
+	// Terms caps how many distinctive terms of the source are searched for.
+	Terms   int
+	Weights FieldWeights
+	Fusion  FusionWeights
+	// Embedder, when set, adds a semantic retriever comparing the mean of
+	// the source's chunk vectors against other chunks. It must be the model
+	// the documents were embedded with.
+	Embedder embedding.Embedder
+}
+
+// WeightedTerm is a term of the source document with its TF-IDF weight.
+type WeightedTerm struct {
+	Term   string
+	Weight float64
+}
+
+type RelatedPage struct {
+	Source pipeline.Document
+	// Terms are the distinctive terms the keyword retriever searched for,
+	// most distinctive first.
+	Terms   []WeightedTerm
+	Results []SearchResult
+}
+
+// FindDocument resolves a document by its numeric id or by its path. Paths
+// may be given without the leading slash.
+func (s *SQLiteDB) FindDocument(ctx context.Context, ref string) (pipeline.Document, error) {
+	var doc pipeline.Document
+	var err error
+
+	if id, convErr := strconv.ParseInt(ref, 10, 64); convErr == nil {
+		doc, err = s.queries.GetDocument(ctx, id)
+	} else {
+		if !strings.HasPrefix(ref, "/") {
+			ref = "/" + ref
+		}
+		doc, err = s.queries.GetDocumentByPath(ctx, ref)
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return doc, fmt.Errorf("no document with id or path %q", ref)
+	}
+	if err != nil {
+		return doc, fmt.Errorf("failed to load document %q: %w", ref, err)
+	}
+	return doc, nil
+}
+
+// RelatedDocuments finds the documents most like the one with the given id.
+// The keyword retriever searches for the source's most distinctive terms by
+// TF-IDF; the semantic retriever, when an embedder is given and the source
+// has vectors, searches for the mean of its chunk vectors. With both, the
+// rankings are fused as in hybrid search.
+func (s *SQLiteDB) RelatedDocuments(ctx context.Context, id int64, opts RelatedOptions) (*RelatedPage, error) {
+	if opts.Terms <= 0 {
+		opts.Terms = DefaultRelatedTerms
+	}
+	if opts.Weights == (FieldWeights{}) {
+		opts.Weights = DefaultFieldWeights
+	}
+	if opts.Fusion == (FusionWeights{}) {
+		opts.Fusion = DefaultFusionWeights
+	}
+
+	source, err := s.queries.GetDocument(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load document %d: %w", id, err)
+	}
+
+	page := &RelatedPage{Source: source}
+
+	page.Terms, err = s.distinctiveTerms(ctx, id, opts.Terms)
+	if err != nil {
+		return nil, err
+	}
+
+	// One extra result makes up for the source turning up in its own list.
+	depth := SearchOptions{
+		Limit:   max(opts.Limit, hybridDepth) + 1,
+		Sort:    SortRelevance,
+		Weights: opts.Weights,
+	}
+
+	var lists []rankedList
+
+	if len(page.Terms) > 0 {
+		group := make([]search.Term, 0, len(page.Terms))
+		for _, t := range page.Terms {
+			group = append(group, search.Term{Text: t.Term})
+		}
+
+		keyword, err := s.keywordSearch(ctx, &search.Query{Groups: [][]search.Term{group}}, depth)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, rankedList{retriever: RetrieverKeyword, weight: opts.Fusion.Keyword, results: without(keyword.Results, id)})
+	}
+
+	if opts.Embedder != nil {
+		vector, err := s.documentVector(ctx, id, opts.Embedder.Model())
+		if err != nil {
+			return nil, err
+		}
+		if vector != nil {
+			semantic, err := s.vectorSearch(ctx, &search.Query{}, opts.Embedder.Model(), vector, depth)
+			if err != nil {
+				return nil, err
+			}
+			lists = append(lists, rankedList{retriever: RetrieverSemantic, weight: opts.Fusion.Semantic, results: without(semantic.Results, id)})
+		}
+	}
+
+	switch len(lists) {
+	case 0:
+	case 1:
+		page.Results = lists[0].results
+	default:
+		page.Results = fuseReciprocalRank(lists, opts.Fusion.K)
+	}
+
+	if len(page.Results) > opts.Limit {
+		page.Results = page.Results[:opts.Limit]
+	}
+
+	if err := s.attachMetadata(ctx, page.Results); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// maxTermShare is the largest share of the corpus a term may appear in and
+// still count as distinctive. Words such as "see" or "com" that most
+// documents use say nothing about which of them are related.
+const maxTermShare = 0.5
+
+// distinctiveTerms returns the n terms of a document with the highest
+// TF-IDF weight. Terms found in no other document cannot lead anywhere and
+// are skipped, as are terms in more than maxTermShare of the documents,
+// stopwords, numbers and very short tokens.
+func (s *SQLiteDB) distinctiveTerms(ctx context.Context, id int64, n int) ([]WeightedTerm, error) {
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents").Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count documents: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, documentTermsQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document terms: %w", err)
+	}
+	defer rows.Close()
+
+	var terms []WeightedTerm
+	for rows.Next() {
+		var term string
+		var tf, df int
+		if err := rows.Scan(&term, &tf, &df); err != nil {
+			return nil, fmt.Errorf("failed to read document term: %w", err)
+		}
+
+		if df < 2 || float64(df) > maxTermShare*float64(total) || !isDistinctive(term) || language.IsStopword(term) {
+			continue
+		}
+
+		weight := (1 + math.Log(float64(tf))) * math.Log(float64(total)/float64(df))
+		terms = append(terms, WeightedTerm{Term: term, Weight: weight})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read document terms: %w", err)
+	}
+
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Weight != terms[j].Weight {
+			return terms[i].Weight > terms[j].Weight
+		}
+		return terms[i].Term < terms[j].Term
+	})
+
+	if len(terms) > n {
+		terms = terms[:n]
+	}
+	return terms, nil
+}
+
+func isDistinctive(term string) bool {
+	if utf8.RuneCountInString(term) < 3 {
+		return false
+	}
+	for _, r := range term {
+		if !unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// documentVector is the normalised mean of a document's chunk vectors, or
+// nil when it has none for model.
+func (s *SQLiteDB) documentVector(ctx context.Context, id int64, model string) ([]float32, error) {
+	blobs, err := s.queries.ListDocumentVectors(ctx, pipeline.ListDocumentVectorsParams{
+		DocumentID: id,
+		Model:      model,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vectors of document %d: %w", id, err)
+	}
+	if len(blobs) == 0 {
+		return nil, nil
+	}
+
+	var mean []float32
+	for _, blob := range blobs {
+		v := decodeVector(blob)
+		embedding.Normalize(v)
+		if mean == nil {
+			mean = make([]float32, len(v))
+		}
+		for i := range min(len(v), len(mean)) {
+			mean[i] += v[i]
+		}
+	}
+
+	embedding.Normalize(mean)
+	return mean, nil
+}
+
+func without(results []SearchResult, id int64) []SearchResult {
+	out := results[:0]
+	for _, r := range results {
+		if r.Document.ID != id {
+			out = append(out, r)
+		}
+	}
+	return out
+}
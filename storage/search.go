@@ -0,0 +1,372 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	pipeline "injestion-pipeline/db"
+	search "injestion-pipeline/query"
+)
+
+const documentColumns = `documents.id, documents.drive_file_id, documents.filename, documents.filepath,
+       documents.content, documents.extension, documents.last_modified, documents.size_bytes`
+
+// Search queries are assembled at runtime because the filters depend on the
+// query, so they live here rather than in query.sql.
+const (
+	// matchWith collects the documents matching the terms as typed, then
+	// those matching only by stem, which the stemmedArms are appended for.
+	matchWith = `WITH hits AS MATERIALIZED (
+  SELECT rowid AS id,
+         -bm25(documents_fts, ?, ?, ?) AS score,
+         highlight(documents_fts, 0, char(2), char(3)) AS title,
+         highlight(documents_fts, 2, char(2), char(3)) AS body
+  FROM documents_fts
+  WHERE documents_fts MATCH ?`
+	matchSelect = `SELECT ` + documentColumns + `,
+       hits.score,
+       COALESCE(hits.title, documents.filename),
+       COALESCE(hits.body, documents.content)`
+	matchFrom = `
+FROM hits
+JOIN documents ON documents.id = hits.id
+WHERE 1 = 1`
+
+	filterSelect = `SELECT ` + documentColumns + `,
+       0.0 AS score,
+       documents.filename,
+       documents.content`
+	filterFrom = `
+FROM documents
+WHERE 1 = 1`
+)
+
+const bestChunksQuery = `SELECT chunks.document_id, chunks.id, chunks.ordinal, chunks.heading,
+       -bm25(chunks_fts, ?, ?, ?) AS score,
+       highlight(chunks_fts, 2, char(2), char(3))
+FROM chunks_fts
+JOIN chunks ON chunks.id = chunks_fts.rowid
+WHERE chunks_fts MATCH ?
+  AND chunks.document_id IN (%s)
+ORDER BY score DESC`
+
+// sortClauses maps each sort order onto its ORDER BY. Every order falls
+// back to the document id so pages never overlap.
+var sortClauses = map[SortOrder]string{
+	SortRelevance: "score DESC, julianday(documents.last_modified) DESC, documents.id",
+	SortModified:  "julianday(documents.last_modified) DESC, documents.id",
+	SortPath:      "documents.filepath, documents.id",
+	SortSize:      "documents.size_bytes DESC, documents.id",
+}
+
+// sqliteTime is the layout julianday() compares against.
+const sqliteTime = "2006-01-02 15:04:05"
+
+func (s *SQLiteDB) SearchDocuments(ctx context.Context, query string, opts SearchOptions) (*SearchPage, error) {
+	frontMatter, err := s.queries.ListFrontMatterKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load front matter keys: %w", err)
+	}
+
+	q, err := search.Parse(query, frontMatter)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Sort == "" {
+		opts.Sort = SortRelevance
+	}
+	if opts.Weights == (FieldWeights{}) {
+		opts.Weights = DefaultFieldWeights
+	}
+	if opts.Fusion == (FusionWeights{}) {
+		opts.Fusion = DefaultFusionWeights
+	}
+	if _, ok := sortClauses[opts.Sort]; !ok {
+		return nil, fmt.Errorf("unknown sort order %q", opts.Sort)
+	}
+
+	if opts.DuplicateThreshold <= 0 {
+		opts.DuplicateThreshold = DefaultDuplicateThreshold
+	}
+	if opts.CollapseDuplicates && opts.DuplicateThreshold < DuplicateClusterThreshold {
+		return nil, fmt.Errorf("duplicate threshold %.2f is below %.2f, the similarity documents are clustered at", opts.DuplicateThreshold, DuplicateClusterThreshold)
+	}
+
+	var page *SearchPage
+	switch {
+	case !opts.AsOf.IsZero() && opts.Comments:
+		return nil, fmt.Errorf("comments cannot be searched as of a date")
+	case !opts.AsOf.IsZero():
+		page, err = s.asOfSearch(ctx, q, opts)
+	case opts.Comments:
+		page, err = s.commentSearch(ctx, q, opts)
+	case opts.Mode == ModeKeyword || opts.Mode == "":
+		page, err = s.keywordSearch(ctx, q, opts)
+	case opts.Mode == ModeSemantic:
+		page, err = s.semanticSearch(ctx, q, opts)
+	case opts.Mode == ModeHybrid:
+		page, err = s.hybridSearch(ctx, q, opts)
+	default:
+		return nil, fmt.Errorf("unknown search mode %q", opts.Mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachMetadata(ctx, page.Results); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (s *SQLiteDB) keywordSearch(ctx context.Context, q *search.Query, opts SearchOptions) (*SearchPage, error) {
+	var with, selectList string
+	var from strings.Builder
+	var args []any
+
+	match := q.Match()
+	if match != "" {
+		arms, armArgs := stemmedArms(q, match, opts.Weights)
+		with = matchWith + arms + "\n)\n"
+		args = append([]any{opts.Weights.Filename, opts.Weights.Path, opts.Weights.Content, match}, armArgs...)
+		selectList = matchSelect
+		from.WriteString(matchFrom)
+	} else {
+		selectList = filterSelect
+		from.WriteString(filterFrom)
+	}
+
+	where, whereArgs := restrictions(q, match == "", opts)
+	from.WriteString(where)
+	args = append(args, whereArgs...)
+
+	page := &SearchPage{Offset: opts.Offset}
+
+	err := s.db.QueryRowContext(ctx, with+"SELECT COUNT(*)"+from.String(), args...).Scan(&page.Total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	pageQuery := with + selectList + from.String() + "\nORDER BY " + sortClauses[opts.Sort] + "\nLIMIT ? OFFSET ?"
+	pageArgs := append(args, opts.Limit, opts.Offset)
+
+	rows, err := s.db.QueryContext(ctx, pageQuery, pageArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search documents: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var doc pipeline.Document
+		var score float64
+		var filenameHighlight, contentHighlight string
+		if err := rows.Scan(
+			&doc.ID,
+			&doc.DriveFileID,
+			&doc.Filename,
+			&doc.Filepath,
+			&doc.Content,
+			&doc.Extension,
+			&doc.LastModified,
+			&doc.SizeBytes,
+			&score,
+			&filenameHighlight,
+			&contentHighlight,
+		); err != nil {
+			return nil, fmt.Errorf("failed to read search result: %w", err)
+		}
+
+		page.Results = append(page.Results, SearchResult{
+			Document:   doc,
+			Title:      parseHighlighted(filenameHighlight),
+			Snippets:   buildSnippets(contentHighlight, snippetMaxFragments),
+			Score:      score,
+			Retrievers: []string{RetrieverKeyword},
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search documents: %w", err)
+	}
+
+	if match != "" && len(page.Results) > 0 {
+		if err := s.attachBestChunks(ctx, page.Results, match, opts.Weights); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// attachBestChunks points each result at its highest-ranked chunk and
+// takes the snippets from that chunk instead of the whole document. A
+// document can match without any single chunk matching, when its terms are
+// spread across sections; such results keep their document-level snippets.
+func (s *SQLiteDB) attachBestChunks(ctx context.Context, results []SearchResult, match string, weights FieldWeights) error {
+	args := []any{weights.Filename, weights.Path, weights.Content, match}
+	for _, r := range results {
+		args = append(args, r.Document.ID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(results)), ", ")
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(bestChunksQuery, placeholders), args...)
+	if err != nil {
+		return fmt.Errorf("failed to rank chunks: %w", err)
+	}
+	defer rows.Close()
+
+	best := make(map[int64]int, len(results))
+	for i, r := range results {
+		best[r.Document.ID] = i
+	}
+
+	for rows.Next() {
+		var documentID int64
+		var section Section
+		var score float64
+		var highlighted string
+		if err := rows.Scan(&documentID, &section.ChunkID, &section.Ordinal, &section.Heading, &score, &highlighted); err != nil {
+			return fmt.Errorf("failed to read chunk: %w", err)
+		}
+
+		i, ok := best[documentID]
+		if !ok {
+			continue
+		}
+		delete(best, documentID)
+
+		results[i].Section = &section
+		results[i].Snippets = buildSnippets(highlighted, snippetMaxFragments)
+	}
+
+	return rows.Err()
+}
+
+// restrictions compiles the query's metadata filters into AND clauses.
+// Excluded terms are added as well when withExclusions is set; keyword
+// searches with positive terms fold them into the MATCH expression instead.
+// Near-duplicates collapsed by opts, and documents that opts.Principal
+// cannot read, are left out.
+func restrictions(q *search.Query, withExclusions bool, opts SearchOptions) (string, []any) {
+	var b strings.Builder
+	var args []any
+
+	if opts.CollapseDuplicates {
+		clause, collapsedArgs := collapsedClause(opts.DuplicateThreshold, opts.Principal)
+		b.WriteString("\n  AND " + clause)
+		args = append(args, collapsedArgs...)
+	}
+
+	if opts.Principal != nil {
+		clause, principalArgs := opts.Principal.readableClause()
+		b.WriteString("\n  AND " + clause)
+		args = append(args, principalArgs...)
+	}
+
+	if exclude := q.ExcludeMatch(); withExclusions && exclude != "" {
+		b.WriteString("\n  AND documents.id NOT IN (SELECT rowid FROM documents_fts WHERE documents_fts MATCH ?)")
+		args = append(args, exclude)
+	}
+
+	for _, f := range q.Filters {
+		clause, filterArgs := filterClause(f)
+		b.WriteString("\n  AND " + clause)
+		args = append(args, filterArgs...)
+	}
+
+	return b.String(), args
+}
+
+// filterClause compiles a metadata filter into a WHERE condition.
+func filterClause(f search.Filter) (string, []any) {
+	var clause string
+	var args []any
+
+	switch f.Field {
+	case "ext":
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(f.Values)), ", ")
+		clause = "documents.extension IN (" + placeholders + ")"
+		for _, v := range f.Values {
+			args = append(args, v)
+		}
+
+	case "path":
+		clause = "documents.filepath REGEXP ?"
+		args = append(args, f.Pattern.String())
+
+	case "modified":
+		clause, args = rangeClause("julianday(documents.last_modified)", "julianday(?)", f.Op,
+			f.From.UTC().Format(sqliteTime), f.Until.UTC().Format(sqliteTime))
+
+	case "created":
+		clause, args = rangeClause("julianday((SELECT created_time FROM document_metadata WHERE document_metadata.document_id = documents.id))",
+			"julianday(?)", f.Op, f.From.UTC().Format(sqliteTime), f.Until.UTC().Format(sqliteTime))
+
+	case "size":
+		clause = fmt.Sprintf("documents.size_bytes %s ?", f.Op)
+		args = append(args, f.Size)
+
+	case "owner":
+		var matches []string
+		for _, v := range f.Values {
+			matches = append(matches, `lower(document_owners.email) LIKE ? ESCAPE '\' OR lower(document_owners.name) LIKE ? ESCAPE '\'`)
+			args = append(args, likePrefix(v), likePrefix(v))
+		}
+		clause = "documents.id IN (SELECT document_owners.document_id FROM document_owners WHERE " + strings.Join(matches, " OR ") + ")"
+
+	case "starred":
+		clause = "COALESCE((SELECT starred FROM document_metadata WHERE document_metadata.document_id = documents.id), FALSE) = ?"
+		args = append(args, f.Flag)
+
+	case "frontmatter":
+		clause, args = frontMatterClause(f.Key, f.Values)
+
+	case "entity":
+		clause, args = entityClause(f.Key, f.Values)
+
+	case "lang":
+		clause, args = languageClause(f.Values)
+	}
+
+	if f.Negate {
+		clause = "NOT (" + clause + ")"
+	}
+	return clause, args
+}
+
+// rangeClause compares column against the interval [from, until) that a
+// date covers, so modified:>2025-01-01 starts after that whole day.
+func rangeClause(column, param, op string, from, until any) (string, []any) {
+	switch op {
+	case ">":
+		return column + " >= " + param, []any{until}
+	case ">=":
+		return column + " >= " + param, []any{from}
+	case "<":
+		return column + " < " + param, []any{from}
+	case "<=":
+		return column + " < " + param, []any{until}
+	default:
+		return "(" + column + " >= " + param + " AND " + column + " < " + param + ")", []any{from, until}
+	}
+}
+
+var regexpCache sync.Map
+
+// regexpMatch backs SQLite's REGEXP operator.
+func regexpMatch(pattern, value string) (bool, error) {
+	if re, ok := regexpCache.Load(pattern); ok {
+		return re.(*regexp.Regexp).MatchString(value), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	regexpCache.Store(pattern, re)
+
+	return re.MatchString(value), nil
+}
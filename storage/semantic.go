@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"injestion-pipeline/embedding"
+	search "injestion-pipeline/query"
+)
+
+const vectorScanQuery = `SELECT chunk_vectors.chunk_id, chunks.document_id, chunk_vectors.vector,
+       documents.filepath, documents.size_bytes, documents.last_modified
+FROM chunk_vectors
+JOIN chunks ON chunks.id = chunk_vectors.chunk_id
+JOIN documents ON documents.id = chunks.document_id
+WHERE chunk_vectors.model = ?`
+
+// vectorHit is the best-scoring chunk of one document, along with the
+// document fields needed to sort hits before the documents are loaded.
+type vectorHit struct {
+	chunkID    int64
+	documentID int64
+	similarity float64
+	path       string
+	size       int64
+	modified   string
+}
+
+func (s *SQLiteDB) semanticSearch(ctx context.Context, q *search.Query, opts SearchOptions) (*SearchPage, error) {
+	if opts.Embedder == nil {
+		return nil, fmt.Errorf("semantic search needs an embedder")
+	}
+
+	text := q.Text()
+	if text == "" {
+		return nil, fmt.Errorf("semantic search needs words to compare, not only filters")
+	}
+
+	vectors, err := opts.Embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	return s.vectorSearch(ctx, q, opts.Embedder.Model(), vectors[0], opts)
+}
+
+// vectorSearch ranks documents by their chunk closest to vector, using the
+// ANN index when it holds vectors of model and scanning them otherwise.
+// Searches that leave documents out or sort by something other than
+// similarity always scan: the index only returns the nearest chunks, which
+// the documents that pass the restrictions, or come first in the order, may
+// not be among.
+func (s *SQLiteDB) vectorSearch(ctx context.Context, q *search.Query, model string, vector []float32, opts SearchOptions) (*SearchPage, error) {
+	var hits []vectorHit
+	var err error
+	if s.indexCovers(model) && !restricted(q, opts) && (opts.Sort == SortRelevance || opts.Sort == "") {
+		hits, err = s.searchIndex(ctx, vector, opts.Offset+opts.Limit)
+	} else {
+		hits, err = s.scanVectors(ctx, q, model, vector, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sortHits(hits, opts.Sort)
+
+	page := &SearchPage{Total: len(hits), Offset: opts.Offset}
+	if opts.Offset >= len(hits) {
+		return page, nil
+	}
+
+	for _, hit := range hits[opts.Offset:min(opts.Offset+opts.Limit, len(hits))] {
+		result, err := s.vectorResult(ctx, hit)
+		if err != nil {
+			return nil, err
+		}
+		page.Results = append(page.Results, result)
+	}
+
+	return page, nil
+}
+
+// restricted reports whether opts or the query leave any document out of
+// the results.
+func restricted(q *search.Query, opts SearchOptions) bool {
+	return len(q.Filters) > 0 || len(q.Exclude) > 0 || opts.Principal != nil || opts.CollapseDuplicates
+}
+
+// scanVectors compares the query vector against every stored chunk vector
+// of the same model that passes the query's filters, keeping the best
+// chunk of each document. Chunks pointing away from the query are dropped.
+func (s *SQLiteDB) scanVectors(ctx context.Context, q *search.Query, model string, query []float32, opts SearchOptions) ([]vectorHit, error) {
+	where, args := restrictions(q, true, opts)
+	args = append([]any{model}, args...)
+
+	rows, err := s.db.QueryContext(ctx, vectorScanQuery+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vectors: %w", err)
+	}
+	defer rows.Close()
+
+	best := make(map[int64]*vectorHit)
+	for rows.Next() {
+		var hit vectorHit
+		var blob []byte
+		if err := rows.Scan(&hit.chunkID, &hit.documentID, &blob, &hit.path, &hit.size, &hit.modified); err != nil {
+			return nil, fmt.Errorf("failed to read vector: %w", err)
+		}
+
+		hit.similarity = embedding.Cosine(query, decodeVector(blob))
+		if hit.similarity <= 0 {
+			continue
+		}
+
+		if prev, ok := best[hit.documentID]; !ok || hit.similarity > prev.similarity {
+			best[hit.documentID] = &hit
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load vectors: %w", err)
+	}
+
+	hits := make([]vectorHit, 0, len(best))
+	for _, hit := range best {
+		hits = append(hits, *hit)
+	}
+	return hits, nil
+}
+
+func (s *SQLiteDB) vectorResult(ctx context.Context, hit vectorHit) (SearchResult, error) {
+	doc, err := s.queries.GetDocument(ctx, hit.documentID)
+	if err != nil {
+		return SearchResult{}, fmt.Errorf("failed to load document %d: %w", hit.documentID, err)
+	}
+
+	chunk, err := s.queries.GetChunk(ctx, hit.chunkID)
+	if err != nil {
+		return SearchResult{}, fmt.Errorf("failed to load chunk %d: %w", hit.chunkID, err)
+	}
+
+	return SearchResult{
+		Document: doc,
+		Title:    Fragment{Text: doc.Filename},
+		Snippets: buildSnippets(chunk.Content, snippetMaxFragments),
+		Section: &Section{
+			ChunkID: chunk.ID,
+			Ordinal: int(chunk.Ordinal),
+			Heading: chunk.Heading,
+		},
+		Score:      hit.similarity,
+		Retrievers: []string{RetrieverSemantic},
+	}, nil
+}
+
+// sortHits orders hits the same way sortClauses orders keyword results.
+func sortHits(hits []vectorHit, order SortOrder) {
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		switch order {
+		case SortModified:
+			if a.modified != b.modified {
+				return a.modified > b.modified
+			}
+		case SortPath:
+			if a.path != b.path {
+				return a.path < b.path
+			}
+		case SortSize:
+			if a.size != b.size {
+				return a.size > b.size
+			}
+		default:
+			if a.similarity != b.similarity {
+				return a.similarity > b.similarity
+			}
+		}
+		return a.documentID < b.documentID
+	})
+}
+
+// encodeVector packs v as little-endian float32s for the vector column.
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// highlight() in the search query wraps every matched token in these
+// control characters. They must stay in sync with char(2) and char(3) in
+// search.go.
+const (
+	highlightOpen  = '\x02'
+	highlightClose = '\x03'
+)
+
+const (
+	snippetMaxFragments = 3
+	snippetContextWords = 8
+	snippetFallbackLen  = 150
+	snippetEllipsis     = "..."
+)
+
+// Highlight is a matched term, as a byte range within Fragment.Text.
+type Highlight struct {
+	Start int
+	End   int
+}
+
+// Fragment is a piece of document text with every matched term marked.
+type Fragment struct {
+	Text       string
+	Highlights []Highlight
+}
+
+type span struct {
+	start int
+	end   int
+}
+
+// parseHighlighted strips the highlight markers from text and returns the
+// plain text together with the byte ranges that were marked.
+func parseHighlighted(text string) Fragment {
+	var b strings.Builder
+	var highlights []Highlight
+	start := -1
+
+	for _, r := range text {
+		switch r {
+		case highlightOpen:
+			start = b.Len()
+		case highlightClose:
+			if start >= 0 {
+				highlights = append(highlights, Highlight{Start: start, End: b.Len()})
+				start = -1
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return Fragment{Text: b.String(), Highlights: highlights}
+}
+
+// buildSnippets turns the output of highlight() into at most maxFragments
+// fragments centred on the matched terms. Windows are cut on word
+// boundaries, so multi-byte characters are never split. When nothing in
+// the text matched, the start of the text is returned instead.
+func buildSnippets(highlighted string, maxFragments int) []Fragment {
+	doc := parseHighlighted(highlighted)
+	text := flattenWhitespace(doc.Text)
+	words := wordSpans(text)
+
+	if len(words) == 0 {
+		return nil
+	}
+
+	if len(doc.Highlights) == 0 {
+		return []Fragment{leadingFragment(text, words)}
+	}
+
+	windows := highlightWindows(doc.Highlights, words)
+
+	sort.SliceStable(windows, func(i, j int) bool {
+		return countHighlights(doc.Highlights, windows[i]) > countHighlights(doc.Highlights, windows[j])
+	})
+	if len(windows) > maxFragments {
+		windows = windows[:maxFragments]
+	}
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].start < windows[j].start
+	})
+
+	fragments := make([]Fragment, 0, len(windows))
+	for _, w := range windows {
+		fragments = append(fragments, cutFragment(text, doc.Highlights, w))
+	}
+
+	return fragments
+}
+
+// highlightWindows expands every highlight by snippetContextWords words on
+// each side and merges the windows that overlap.
+func highlightWindows(highlights []Highlight, words []span) []span {
+	var windows []span
+
+	for _, h := range highlights {
+		first := sort.Search(len(words), func(i int) bool { return words[i].end > h.Start })
+		last := sort.Search(len(words), func(i int) bool { return words[i].start >= h.End }) - 1
+		first = max(first-snippetContextWords, 0)
+		last = max(min(last+snippetContextWords, len(words)-1), first)
+
+		w := span{start: words[first].start, end: words[last].end}
+		if n := len(windows); n > 0 && w.start <= windows[n-1].end {
+			windows[n-1].end = max(windows[n-1].end, w.end)
+			continue
+		}
+		windows = append(windows, w)
+	}
+
+	return windows
+}
+
+func cutFragment(text string, highlights []Highlight, w span) Fragment {
+	var f Fragment
+	offset := w.start
+
+	if w.start > 0 {
+		f.Text = snippetEllipsis
+		offset -= len(snippetEllipsis)
+	}
+	f.Text += text[w.start:w.end]
+	if w.end < len(text) {
+		f.Text += snippetEllipsis
+	}
+
+	for _, h := range highlights {
+		if h.Start >= w.start && h.End <= w.end {
+			f.Highlights = append(f.Highlights, Highlight{
+				Start: h.Start - offset,
+				End:   h.End - offset,
+			})
+		}
+	}
+
+	return f
+}
+
+func leadingFragment(text string, words []span) Fragment {
+	end := words[0].end
+	for _, w := range words {
+		if w.end > snippetFallbackLen {
+			break
+		}
+		end = w.end
+	}
+
+	f := Fragment{Text: text[words[0].start:end]}
+	if end < len(text) {
+		f.Text += snippetEllipsis
+	}
+	return f
+}
+
+func countHighlights(highlights []Highlight, w span) int {
+	n := 0
+	for _, h := range highlights {
+		if h.Start >= w.start && h.End <= w.end {
+			n++
+		}
+	}
+	return n
+}
+
+// wordSpans returns the byte ranges of the whitespace-separated words in text.
+func wordSpans(text string) []span {
+	var words []span
+	start := -1
+
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				words = append(words, span{start: start, end: i})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		words = append(words, span{start: start, end: len(text)})
+	}
+
+	return words
+}
+
+// flattenWhitespace replaces line breaks and tabs with spaces. Each is a
+// one-byte swap, so highlight offsets stay valid.
+func flattenWhitespace(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == '\t' {
+			return ' '
+		}
+		return r
+	}, text)
+}
@@ -3,30 +3,57 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
 	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/dedupe"
 	"injestion-pipeline/models"
+	"injestion-pipeline/vectorindex"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
+// driverName is go-sqlite3 with the extra SQL functions the search queries
+// rely on registered on every connection.
+const driverName = "sqlite3_pipeline"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("regexp", regexpMatch, true); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("simhash_similarity", simhashSimilarity, true)
+		},
+	})
+}
+
 type SQLiteDB struct {
 	db      *sql.DB
 	queries *pipeline.Queries
 	dbPath  string
+
+	versionLimit int
+
+	// index is the ANN index over chunk vectors, nil when there is none.
+	// indexErr holds why an existing index file could not be loaded.
+	index      *vectorindex.HNSW
+	indexErr   error
+	indexDirty bool
 }
 
 func NewSQLiteDB(dbPath string) *SQLiteDB {
 	return &SQLiteDB{
-		dbPath: dbPath,
+		dbPath:       dbPath,
+		versionLimit: DefaultVersionLimit,
 	}
 }
 
 func (s *SQLiteDB) Initialize() error {
-	db, err := sql.Open("sqlite3", s.dbPath)
+	db, err := sql.Open(driverName, s.dbPath)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
@@ -41,7 +68,7 @@ func (s *SQLiteDB) Initialize() error {
 		return fmt.Errorf("failed to read schema file: %w", err)
 	}
 
-	_, err = db.Exec(string(schema))
+	err = applySchema(db, string(schema))
 	if err != nil {
 		if strings.Contains(err.Error(), "fts5") || strings.Contains(err.Error(), "no such module") {
 			return fmt.Errorf("SQLite FTS5 is not enabled. Rebuild with: go build -tags 'fts5'")
@@ -52,11 +79,48 @@ func (s *SQLiteDB) Initialize() error {
 	s.db = db
 	s.queries = pipeline.New(db)
 
-	return nil
+	return s.loadVectorIndex()
 }
 
 func (s *SQLiteDB) SaveDocument(ctx context.Context, doc *models.Document) error {
-	_, err := s.queries.CreateDocument(ctx, pipeline.CreateDocumentParams{
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := s.queries.WithTx(tx)
+
+	// A document is stored once per Drive file: saving it again replaces
+	// the earlier copy, and the triggers clear out its chunks and vectors.
+	var previous *pipeline.Document
+	prev, err := queries.GetDocumentByDriveFileID(ctx, doc.DriveFileID)
+	switch {
+	case err == nil:
+		previous = &prev
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("failed to look up previous version: %w", err)
+	}
+
+	replaced, err := queries.ListChunkIDsByDriveFileID(ctx, doc.DriveFileID)
+	if err != nil {
+		return fmt.Errorf("failed to look up previous version: %w", err)
+	}
+	var keptComments []pipeline.Comment
+	if doc.Comments == nil && previous != nil {
+		keptComments, err = queries.ListDocumentComments(ctx, previous.ID)
+		if err != nil {
+			return fmt.Errorf("failed to look up previous comments: %w", err)
+		}
+	}
+	if err := queries.DeleteDocumentsByDriveFileID(ctx, doc.DriveFileID); err != nil {
+		return fmt.Errorf("failed to replace previous version: %w", err)
+	}
+
+	var chunkIDs []int64
+	var vectors [][]float32
+
+	saved, err := queries.CreateDocument(ctx, pipeline.CreateDocumentParams{
 		DriveFileID:  doc.DriveFileID,
 		Filename:     doc.FileName,
 		Filepath:     doc.FilePath,
@@ -70,28 +134,141 @@ func (s *SQLiteDB) SaveDocument(ctx context.Context, doc *models.Document) error
 		return fmt.Errorf("failed to save document: %w", err)
 	}
 
-	return nil
-}
-
-func (s *SQLiteDB) SearchDocuments(ctx context.Context, query string, limit int) ([]SearchResult, error) {
-	docs, err := s.queries.SearchDocuments(ctx, pipeline.SearchDocumentsParams{
-		Query: query,
-		Limit: int64(limit),
+	contentHash := doc.ContentHash
+	if contentHash == "" {
+		contentHash = md5Hex(doc.Content)
+	}
+	err = queries.CreateDocumentHash(ctx, pipeline.CreateDocumentHashParams{
+		DocumentID:  saved.ID,
+		Md5Checksum: contentHash,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to search documents: %w", err)
+		return fmt.Errorf("failed to save content hash: %w", err)
+	}
+
+	if err := s.recordVersion(ctx, queries, previous, doc, contentHash); err != nil {
+		return err
+	}
+
+	// Documents too short to sign are in no cluster, so they are never
+	// reported or hidden as duplicates.
+	if dedupe.Signable(doc.Content) {
+		simhash := int64(dedupe.SimHash(doc.Content))
+		err = queries.CreateDocumentSignature(ctx, pipeline.CreateDocumentSignatureParams{
+			DocumentID: saved.ID,
+			Simhash:    simhash,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save document signature: %w", err)
+		}
+
+		if err := assignCluster(ctx, queries, saved.ID, simhash); err != nil {
+			return err
+		}
+	}
+
+	if err := saveMetadata(ctx, queries, saved.ID, doc.Metadata); err != nil {
+		return err
+	}
+
+	if err := saveComments(ctx, queries, saved.ID, doc.Comments, keptComments); err != nil {
+		return err
+	}
+
+	if err := saveFrontMatter(ctx, queries, saved.ID, doc.FrontMatter); err != nil {
+		return err
+	}
+
+	if err := saveLinks(ctx, queries, saved.ID, doc.Links); err != nil {
+		return err
+	}
+
+	if err := saveEntities(ctx, queries, saved.ID, doc.Entities); err != nil {
+		return err
+	}
+
+	if err := saveLanguage(ctx, tx, queries, saved.ID, doc); err != nil {
+		return err
+	}
+
+	if err := saveSummary(ctx, queries, saved.ID, doc); err != nil {
+		return err
 	}
 
-	results := make([]SearchResult, 0, len(docs))
-	for _, doc := range docs {
-		snippet := generateSnippet(doc.Content, query, 150)
-		results = append(results, SearchResult{
-			Document: doc,
-			Snippet:  snippet,
+	for _, chunk := range doc.Chunks {
+		chunkID, err := queries.CreateChunk(ctx, pipeline.CreateChunkParams{
+			DocumentID: saved.ID,
+			Ordinal:    int64(chunk.Ordinal),
+			Heading:    chunk.Heading,
+			Content:    chunk.Content,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save chunk %d: %w", chunk.Ordinal, err)
+		}
+
+		if len(chunk.Vector) == 0 {
+			continue
+		}
+
+		err = queries.CreateChunkVector(ctx, pipeline.CreateChunkVectorParams{
+			ChunkID: chunkID,
+			Model:   doc.EmbeddingModel,
+			Dims:    int64(len(chunk.Vector)),
+			Vector:  encodeVector(chunk.Vector),
 		})
+		if err != nil {
+			return fmt.Errorf("failed to save vector for chunk %d: %w", chunk.Ordinal, err)
+		}
+		chunkIDs = append(chunkIDs, chunkID)
+		vectors = append(vectors, chunk.Vector)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit document: %w", err)
 	}
 
-	return results, nil
+	s.unindexChunks(replaced)
+	return s.indexChunks(ctx, doc.EmbeddingModel, chunkIDs, vectors)
+}
+
+// DeleteDocument removes the stored copy of a Drive file along with every
+// version kept of it.
+func (s *SQLiteDB) DeleteDocument(ctx context.Context, driveFileID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := s.queries.WithTx(tx)
+
+	removed, err := queries.ListChunkIDsByDriveFileID(ctx, driveFileID)
+	if err != nil {
+		return fmt.Errorf("failed to look up document chunks: %w", err)
+	}
+	if err := queries.DeleteDocumentsByDriveFileID(ctx, driveFileID); err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	if err := queries.DeleteDocumentVersions(ctx, driveFileID); err != nil {
+		return fmt.Errorf("failed to delete document versions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit deletion: %w", err)
+	}
+
+	s.unindexChunks(removed)
+	return nil
+}
+
+// ListContentHashes returns the Drive id, path and content hash of every
+// stored document, so a crawl can skip files that have not changed.
+func (s *SQLiteDB) ListContentHashes(ctx context.Context) ([]pipeline.ListContentHashesRow, error) {
+	hashes, err := s.queries.ListContentHashes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list content hashes: %w", err)
+	}
+	return hashes, nil
 }
 
 func (s *SQLiteDB) ListAllDocuments(ctx context.Context) ([]pipeline.Document, error) {
@@ -107,49 +284,19 @@ func (s *SQLiteDB) ClearAll(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to clear documents: %w", err)
 	}
-	return nil
+	if err := s.queries.DeleteAllDocumentVersions(ctx); err != nil {
+		return fmt.Errorf("failed to clear document versions: %w", err)
+	}
+	return s.resetVectorIndex()
 }
 
+// Close compacts the vector index if enough of it was removed and saves it
+// if documents were added since it was loaded, then closes the database.
 func (s *SQLiteDB) Close() error {
+	err := s.compactVectorIndex(context.Background())
+	err = errors.Join(err, s.saveVectorIndex())
 	if s.db != nil {
-		return s.db.Close()
-	}
-	return nil
-}
-
-func generateSnippet(content string, query string, maxLength int) string {
-	queryLower := strings.ToLower(query)
-	contentLower := strings.ToLower(content)
-
-	queryTerms := strings.Fields(queryLower)
-	if len(queryTerms) == 0 {
-		if len(content) > maxLength {
-			return content[:maxLength] + "..."
-		}
-		return content
-	}
-
-	firstTerm := queryTerms[0]
-	index := strings.Index(contentLower, firstTerm)
-
-	if index == -1 {
-		if len(content) > maxLength {
-			return content[:maxLength] + "..."
-		}
-		return content
+		err = errors.Join(err, s.db.Close())
 	}
-
-	start := max(index-50, 0)
-	end := min(index+len(firstTerm)+100, len(content))
-	snippet := content[start:end]
-
-	if start > 0 {
-		snippet = "..." + snippet
-	}
-
-	if end < len(content) {
-		snippet = snippet + "..."
-	}
-
-	return snippet
+	return err
 }
@@ -2,20 +2,147 @@ package storage
 
 import (
 	"context"
+	"time"
+
 	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/embedding"
 	"injestion-pipeline/models"
 )
 
 type Database interface {
 	Initialize() error
 	SaveDocument(ctx context.Context, doc *models.Document) error
-	SearchDocuments(ctx context.Context, query string, limit int) ([]SearchResult, error)
+	SearchDocuments(ctx context.Context, query string, opts SearchOptions) (*SearchPage, error)
+	FindDocument(ctx context.Context, ref string) (pipeline.Document, error)
+	RelatedDocuments(ctx context.Context, id int64, opts RelatedOptions) (*RelatedPage, error)
 	ListAllDocuments(ctx context.Context) ([]pipeline.Document, error)
+	ListContentHashes(ctx context.Context) ([]pipeline.ListContentHashesRow, error)
 	ClearAll(ctx context.Context) error
 	Close() error
 }
 
+// FieldWeights scales the BM25 contribution of each indexed column, so a
+// match in the filename can be made to count for more than one in the body.
+type FieldWeights struct {
+	Filename float64
+	Path     float64
+	Content  float64
+}
+
+var DefaultFieldWeights = FieldWeights{
+	Filename: 10.0,
+	Path:     5.0,
+	Content:  1.0,
+}
+
+// SortOrder selects how search results are ordered.
+type SortOrder string
+
+const (
+	SortRelevance SortOrder = "relevance"
+	SortModified  SortOrder = "modified"
+	SortPath      SortOrder = "path"
+	SortSize      SortOrder = "size"
+)
+
+var SortOrders = []SortOrder{SortRelevance, SortModified, SortPath, SortSize}
+
+// SearchMode selects the retriever that answers a search.
+type SearchMode string
+
+const (
+	// ModeKeyword ranks full-text matches by BM25.
+	ModeKeyword SearchMode = "keyword"
+	// ModeSemantic ranks chunks by the cosine similarity of their vectors
+	// to the query's, so paraphrases match without sharing any words.
+	ModeSemantic SearchMode = "semantic"
+	// ModeHybrid runs both retrievers and fuses their rankings.
+	ModeHybrid SearchMode = "hybrid"
+)
+
+var SearchModes = []SearchMode{ModeKeyword, ModeSemantic, ModeHybrid}
+
+// Retriever names, as reported in SearchResult.Retrievers.
+const (
+	RetrieverKeyword  = "keyword"
+	RetrieverSemantic = "semantic"
+)
+
+type SearchOptions struct {
+	Limit   int
+	Offset  int
+	Sort    SortOrder
+	Mode    SearchMode
+	Weights FieldWeights
+	// Fusion weighs the retrievers against each other in hybrid mode.
+	Fusion FusionWeights
+	// Embedder turns the query into a vector in semantic and hybrid mode.
+	// It must be the same model the documents were embedded with at ingest.
+	Embedder embedding.Embedder
+	// CollapseDuplicates leaves out documents that are at least
+	// DuplicateThreshold similar to a newer document of their cluster.
+	CollapseDuplicates bool
+	DuplicateThreshold float64
+	// AsOf, when set, answers a keyword search from the versions of each
+	// document that were current at that time.
+	AsOf time.Time
+	// Principal, when set, limits results to documents that person can
+	// read according to their Drive permissions.
+	Principal *Principal
+	// Comments matches the query against Drive comments and replies rather
+	// than document text, in keyword mode.
+	Comments bool
+}
+
+// SearchPage is one page of search results together with the number of
+// documents that matched in total.
+type SearchPage struct {
+	Results []SearchResult
+	Total   int
+	Offset  int
+}
+
+// Section identifies the chunk of a document that best matched a query.
+type Section struct {
+	ChunkID int64
+	Ordinal int
+	// Heading is the breadcrumb of headings above the chunk.
+	Heading string
+}
+
 type SearchResult struct {
 	Document pipeline.Document
-	Snippet  string
+	// Title is the filename with any matched terms marked.
+	Title    Fragment
+	Snippets []Fragment
+	// Section is the chunk that matched best, if any did on its own.
+	Section *Section
+	// Score is the negated BM25 rank in keyword mode, the cosine
+	// similarity in semantic mode and the fused reciprocal rank in hybrid
+	// mode. Either way, higher means more relevant.
+	Score float64
+	// Retrievers lists the retrievers that returned this document.
+	Retrievers []string
+	// Version is the stored version that matched in an as-of search.
+	Version *VersionRef
+	// Comments are the matching comments in a comment search.
+	Comments []CommentHit
+	// Metadata is what Drive reported about the document, if anything.
+	Metadata *models.Metadata
+	// FrontMatter holds the fields of the document's markdown front matter.
+	FrontMatter []models.Field
+	// Language is the language detected in the document, if it is one
+	// that is stemmed.
+	Language string
+	// Summary is the gist of the document picked at ingest, and Keywords
+	// the terms that set it apart, most distinctive first.
+	Summary  string
+	Keywords []string
+}
+
+// VersionRef identifies a stored version of a document.
+type VersionRef struct {
+	Number int64
+	// Author is who made the Drive revision, when the version came from one.
+	Author string
 }
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/language"
+	"injestion-pipeline/models"
+)
+
+// DefaultKeywords is how many keywords are kept for each document.
+const DefaultKeywords = 8
+
+// keywordBatch is how many documents RefreshKeywords reads at a time.
+const keywordBatch = 100
+
+// vocabBatch is how many terms each document frequency lookup asks for,
+// well under SQLite's limit on query parameters.
+const vocabBatch = 500
+
+const documentFrequencyQuery = `SELECT term, doc FROM documents_vocab_rows WHERE term IN (%s)`
+
+// saveSummary stores the summary of a newly saved document. Its keywords
+// are weighed against the whole corpus, so they are left to
+// RefreshKeywords.
+func saveSummary(ctx context.Context, queries *pipeline.Queries, documentID int64, doc *models.Document) error {
+	if doc.Summary == "" {
+		return nil
+	}
+	err := queries.CreateDocumentSummary(ctx, pipeline.CreateDocumentSummaryParams{
+		DocumentID: documentID,
+		Summary:    doc.Summary,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save document summary: %w", err)
+	}
+	return nil
+}
+
+// RefreshKeywords works out the keywords of every stored document again.
+// How distinctive a term is depends on the documents stored alongside it,
+// so ingest calls this once a whole batch is saved; the keywords then do
+// not depend on the order the documents were saved in.
+func (s *SQLiteDB) RefreshKeywords(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := refreshKeywords(ctx, tx, s.queries.WithTx(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit keywords: %w", err)
+	}
+	return nil
+}
+
+func refreshKeywords(ctx context.Context, tx *sql.Tx, queries *pipeline.Queries) error {
+	var total int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents").Scan(&total); err != nil {
+		return fmt.Errorf("failed to count documents: %w", err)
+	}
+
+	var after int64
+	for {
+		docs, err := queries.ListDocumentContents(ctx, pipeline.ListDocumentContentsParams{
+			AfterID: after,
+			Limit:   keywordBatch,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list documents: %w", err)
+		}
+		if len(docs) == 0 {
+			break
+		}
+		for _, doc := range docs {
+			if err := saveKeywords(ctx, tx, queries, doc.ID, doc.Content, total); err != nil {
+				return err
+			}
+		}
+		after = docs[len(docs)-1].ID
+	}
+	return nil
+}
+
+// saveKeywords replaces the keywords of a document with those of content
+// in a corpus of total documents.
+func saveKeywords(ctx context.Context, tx *sql.Tx, queries *pipeline.Queries, documentID int64, content string, total int) error {
+	if err := queries.DeleteDocumentKeywords(ctx, documentID); err != nil {
+		return fmt.Errorf("failed to clear keywords: %w", err)
+	}
+
+	keywords, err := documentKeywords(ctx, tx, content, total, DefaultKeywords)
+	if err != nil {
+		return err
+	}
+	for _, k := range keywords {
+		err := queries.CreateDocumentKeyword(ctx, pipeline.CreateDocumentKeywordParams{
+			DocumentID: documentID,
+			Keyword:    k.Term,
+			Weight:     k.Weight,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save keyword %s: %w", k.Term, err)
+		}
+	}
+	return nil
+}
+
+// documentKeywords returns the n terms of content with the highest TF-IDF
+// weight. Unlike distinctiveTerms, terms found in no other document count,
+// since they are often what the document is about; the inverse document
+// frequency is smoothed so that they do not swamp everything else, and so
+// that a corpus of one document still has keywords. Stopwords, numbers and
+// very short tokens are skipped.
+func documentKeywords(ctx context.Context, tx *sql.Tx, content string, total, n int) ([]WeightedTerm, error) {
+	counts := make(map[string]int)
+	for _, w := range language.Words(content) {
+		if isDistinctive(w) && !language.IsStopword(w) {
+			counts[w]++
+		}
+	}
+	if len(counts) == 0 {
+		return nil, nil
+	}
+
+	terms := make([]string, 0, len(counts))
+	for term := range counts {
+		terms = append(terms, term)
+	}
+	frequencies, err := documentFrequencies(ctx, tx, terms)
+	if err != nil {
+		return nil, err
+	}
+
+	weighted := make([]WeightedTerm, 0, len(counts))
+	for term, tf := range counts {
+		// A term missing from the index, because it is spelt differently
+		// there, is at least in this document.
+		df := max(frequencies[indexForm(term)], 1)
+		weight := (1 + math.Log(float64(tf))) * math.Log(1+float64(total)/float64(df))
+		weighted = append(weighted, WeightedTerm{Term: term, Weight: weight})
+	}
+
+	sort.Slice(weighted, func(i, j int) bool {
+		if weighted[i].Weight != weighted[j].Weight {
+			return weighted[i].Weight > weighted[j].Weight
+		}
+		return weighted[i].Term < weighted[j].Term
+	})
+	if len(weighted) > n {
+		weighted = weighted[:n]
+	}
+	return weighted, nil
+}
+
+// documentFrequencies looks up how many documents each term appears in,
+// keyed by the term as the index spells it.
+func documentFrequencies(ctx context.Context, tx *sql.Tx, terms []string) (map[string]int, error) {
+	frequencies := make(map[string]int, len(terms))
+	for start := 0; start < len(terms); start += vocabBatch {
+		batch := terms[start:min(start+vocabBatch, len(terms))]
+		args := make([]any, 0, len(batch))
+		for _, term := range batch {
+			args = append(args, indexForm(term))
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", ")
+
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(documentFrequencyQuery, placeholders), args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document frequencies: %w", err)
+		}
+		for rows.Next() {
+			var term string
+			var df int
+			if err := rows.Scan(&term, &df); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to read document frequency: %w", err)
+			}
+			frequencies[term] = df
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document frequencies: %w", err)
+		}
+	}
+	return frequencies, nil
+}
+
+// indexForm spells a lowercased term the way the full-text tokenizer
+// indexes it, which strips the diacritics from Latin letters.
+func indexForm(term string) string {
+	var b strings.Builder
+	latin := false
+	for _, r := range norm.NFD.String(term) {
+		if unicode.Is(unicode.Mn, r) {
+			if !latin {
+				b.WriteRune(r)
+			}
+			continue
+		}
+		latin = unicode.Is(unicode.Latin, r)
+		b.WriteRune(r)
+	}
+	return norm.NFC.String(b.String())
+}
+
+// DocumentSummary returns the summary stored for a document and its
+// keywords, most distinctive first. Documents ingested before summaries
+// were made have neither.
+func (s *SQLiteDB) DocumentSummary(ctx context.Context, documentID int64) (string, []string, error) {
+	summary, err := s.queries.GetDocumentSummary(ctx, documentID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", nil, fmt.Errorf("failed to load document summary: %w", err)
+	}
+
+	keywords, err := s.queries.ListDocumentKeywords(ctx, documentID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load document keywords: %w", err)
+	}
+	return summary, keywords, nil
+}
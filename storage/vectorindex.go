@@ -0,0 +1,362 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"injestion-pipeline/embedding"
+	"injestion-pipeline/vectorindex"
+)
+
+// vectorIndexSuffix names the ANN index file kept next to the database.
+const vectorIndexSuffix = ".hnsw"
+
+// annOversample is how many chunks the index is asked for per wanted
+// document. Several chunks of one document can crowd the neighbourhood, so
+// it has to over-fetch.
+const (
+	annOversample    = 8
+	annMinCandidates = 200
+)
+
+// compactRatio is the share of removed vectors at which the index is
+// rebuilt without them. Every re-ingest replaces a document's chunks, so
+// without compaction they pile up and take candidates from live vectors.
+const compactRatio = 0.25
+
+const storedVectorsQuery = `SELECT chunk_id, vector FROM chunk_vectors WHERE model = ?`
+
+const candidateChunksQuery = `SELECT chunks.id, chunks.document_id,
+       documents.filepath, documents.size_bytes, documents.last_modified
+FROM chunks
+JOIN documents ON documents.id = chunks.document_id
+WHERE chunks.id IN (%s)`
+
+// IndexStats describes the ANN index and how closely it agrees with an
+// exact scan over the same vectors.
+type IndexStats struct {
+	Path      string
+	FileBytes int64
+	Model     string
+	Dims      int
+	Vectors   int
+	Deleted   int
+	// Stored is the number of vectors of Model in the database. It differs
+	// from Vectors when the index is stale.
+	Stored int
+
+	Samples int
+	K       int
+	// Recall is the mean fraction of the exact top K that the index also
+	// returned, over Samples stored vectors used as queries.
+	Recall       float64
+	IndexLatency time.Duration
+	ExactLatency time.Duration
+}
+
+func (s *SQLiteDB) vectorIndexPath() string {
+	return s.dbPath + vectorIndexSuffix
+}
+
+// loadVectorIndex opens the index file if there is one. A missing or
+// unreadable file is not fatal: search falls back to scanning every vector,
+// and the next save builds the index again from the stored vectors.
+func (s *SQLiteDB) loadVectorIndex() error {
+	index, err := vectorindex.Load(s.vectorIndexPath())
+	if err == nil {
+		s.index = index
+		return nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		s.indexErr = err
+	}
+	return nil
+}
+
+// indexChunks adds freshly saved chunk vectors to the index. When there is
+// no index, it is built from every stored vector of model, which already
+// includes these.
+func (s *SQLiteDB) indexChunks(ctx context.Context, model string, ids []int64, vectors [][]float32) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if s.index == nil {
+		_, err := s.RebuildVectorIndex(ctx, model)
+		return err
+	}
+	if s.index.Model() != model {
+		return nil
+	}
+
+	for i, id := range ids {
+		if err := s.index.Add(id, vectors[i]); err != nil {
+			return fmt.Errorf("failed to index chunk %d: %w", id, err)
+		}
+	}
+	s.indexDirty = true
+
+	return nil
+}
+
+// unindexChunks removes the vectors of replaced chunks from the index.
+func (s *SQLiteDB) unindexChunks(ids []int64) {
+	if s.index == nil || len(ids) == 0 {
+		return
+	}
+	for _, id := range ids {
+		s.index.Remove(id)
+	}
+	s.indexDirty = true
+}
+
+// compactVectorIndex rebuilds the index once removed vectors make up
+// compactRatio of it.
+func (s *SQLiteDB) compactVectorIndex(ctx context.Context) error {
+	if s.index == nil {
+		return nil
+	}
+	deleted := s.index.Deleted()
+	if deleted == 0 || float64(deleted) < compactRatio*float64(deleted+s.index.Len()) {
+		return nil
+	}
+	if s.index.Len() == 0 {
+		return s.resetVectorIndex()
+	}
+	_, err := s.RebuildVectorIndex(ctx, s.index.Model())
+	return err
+}
+
+// resetVectorIndex drops the index along with every document, so the next
+// save starts a new one.
+func (s *SQLiteDB) resetVectorIndex() error {
+	s.index = nil
+	s.indexErr = nil
+	s.indexDirty = false
+
+	if err := os.Remove(s.vectorIndexPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove vector index: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteDB) saveVectorIndex() error {
+	if s.index == nil || !s.indexDirty {
+		return nil
+	}
+	if err := s.index.Save(s.vectorIndexPath()); err != nil {
+		return fmt.Errorf("failed to save vector index: %w", err)
+	}
+	s.indexDirty = false
+	return nil
+}
+
+// RebuildVectorIndex replaces the index with a fresh one over every stored
+// vector of model, dropping removed entries and fixing a stale or broken
+// index file. It returns the number of vectors indexed.
+func (s *SQLiteDB) RebuildVectorIndex(ctx context.Context, model string) (int, error) {
+	ids, vectors, err := s.storedVectors(ctx, model)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("no vectors stored for model %q", model)
+	}
+
+	index := vectorindex.New(model, len(vectors[0]), vectorindex.DefaultConfig)
+	for i, id := range ids {
+		if err := index.Add(id, vectors[i]); err != nil {
+			return 0, fmt.Errorf("failed to index chunk %d: %w", id, err)
+		}
+	}
+
+	s.index = index
+	s.indexErr = nil
+	s.indexDirty = true
+	if err := s.saveVectorIndex(); err != nil {
+		return 0, err
+	}
+
+	return len(ids), nil
+}
+
+// VectorIndexStats reports on the index and measures it against an exact
+// scan, using samples randomly chosen stored vectors as queries.
+func (s *SQLiteDB) VectorIndexStats(ctx context.Context, samples, k int) (*IndexStats, error) {
+	if s.indexErr != nil {
+		return nil, fmt.Errorf("vector index is unreadable, rebuild it: %w", s.indexErr)
+	}
+	if s.index == nil {
+		return nil, fmt.Errorf("no vector index at %s, build one first", s.vectorIndexPath())
+	}
+
+	stats := &IndexStats{
+		Path:    s.vectorIndexPath(),
+		Model:   s.index.Model(),
+		Dims:    s.index.Dims(),
+		Vectors: s.index.Len(),
+		Deleted: s.index.Deleted(),
+		K:       k,
+	}
+	if info, err := os.Stat(stats.Path); err == nil {
+		stats.FileBytes = info.Size()
+	}
+
+	ids, vectors, err := s.storedVectors(ctx, stats.Model)
+	if err != nil {
+		return nil, err
+	}
+	stats.Stored = len(ids)
+	if len(ids) == 0 || samples <= 0 || k <= 0 {
+		return stats, nil
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	picks := rng.Perm(len(ids))[:min(samples, len(ids))]
+	stats.Samples = len(picks)
+
+	var found, wanted int
+	var indexTime, exactTime time.Duration
+
+	for _, p := range picks {
+		query := vectors[p]
+
+		start := time.Now()
+		approx := s.index.Search(query, k)
+		indexTime += time.Since(start)
+
+		start = time.Now()
+		exact := exactNeighbors(query, ids, vectors, k)
+		exactTime += time.Since(start)
+
+		returned := make(map[int64]bool, len(approx))
+		for _, n := range approx {
+			returned[n.ID] = true
+		}
+		for _, id := range exact {
+			if returned[id] {
+				found++
+			}
+		}
+		wanted += len(exact)
+	}
+
+	if wanted > 0 {
+		stats.Recall = float64(found) / float64(wanted)
+	}
+	stats.IndexLatency = indexTime / time.Duration(stats.Samples)
+	stats.ExactLatency = exactTime / time.Duration(stats.Samples)
+
+	return stats, nil
+}
+
+func (s *SQLiteDB) storedVectors(ctx context.Context, model string) ([]int64, [][]float32, error) {
+	rows, err := s.db.QueryContext(ctx, storedVectorsQuery, model)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load vectors: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	var vectors [][]float32
+	for rows.Next() {
+		var id int64
+		var blob []byte
+		if err := rows.Scan(&id, &blob); err != nil {
+			return nil, nil, fmt.Errorf("failed to read vector: %w", err)
+		}
+		ids = append(ids, id)
+		vectors = append(vectors, decodeVector(blob))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to load vectors: %w", err)
+	}
+
+	return ids, vectors, nil
+}
+
+// exactNeighbors is the brute-force top k the index is measured against.
+func exactNeighbors(query []float32, ids []int64, vectors [][]float32, k int) []int64 {
+	type scored struct {
+		id  int64
+		sim float64
+	}
+
+	all := make([]scored, len(ids))
+	for i, id := range ids {
+		all[i] = scored{id: id, sim: embedding.Cosine(query, vectors[i])}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].sim > all[j].sim })
+
+	out := make([]int64, 0, k)
+	for _, s := range all[:min(k, len(all))] {
+		out = append(out, s.id)
+	}
+	return out
+}
+
+// indexCovers reports whether semantic search for model can use the index.
+func (s *SQLiteDB) indexCovers(model string) bool {
+	return s.index != nil && s.index.Model() == model && s.index.Len() > 0
+}
+
+// searchIndex is scanVectors backed by the ANN index: it asks the index for
+// the chunks nearest the query and keeps the best chunk of each document.
+// vectorSearch only uses it when no document is left out, so the nearest
+// documents are among the candidates; those beyond the candidate set are
+// never seen, so Total counts the candidates rather than every document.
+func (s *SQLiteDB) searchIndex(ctx context.Context, query []float32, want int) ([]vectorHit, error) {
+	neighbors := s.index.Search(query, max(want*annOversample, annMinCandidates))
+	if len(neighbors) == 0 {
+		return nil, nil
+	}
+
+	similarity := make(map[int64]float64, len(neighbors))
+	args := make([]any, 0, len(neighbors))
+	for _, n := range neighbors {
+		if n.Similarity <= 0 {
+			continue
+		}
+		similarity[n.ID] = n.Similarity
+		args = append(args, n.ID)
+	}
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(candidateChunksQuery, placeholders), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load candidate chunks: %w", err)
+	}
+	defer rows.Close()
+
+	best := make(map[int64]*vectorHit)
+	for rows.Next() {
+		var hit vectorHit
+		if err := rows.Scan(&hit.chunkID, &hit.documentID, &hit.path, &hit.size, &hit.modified); err != nil {
+			return nil, fmt.Errorf("failed to read candidate chunk: %w", err)
+		}
+		hit.similarity = similarity[hit.chunkID]
+
+		if prev, ok := best[hit.documentID]; !ok || hit.similarity > prev.similarity {
+			best[hit.documentID] = &hit
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load candidate chunks: %w", err)
+	}
+
+	hits := make([]vectorHit, 0, len(best))
+	for _, hit := range best {
+		hits = append(hits, *hit)
+	}
+	return hits, nil
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	pipeline "injestion-pipeline/db"
+	"injestion-pipeline/models"
+)
+
+// DefaultVersionLimit is how many versions of each document are kept.
+const DefaultVersionLimit = 20
+
+// KeepVersions caps the versions kept per document; the oldest are pruned
+// when a new one is saved. Zero keeps every version.
+func (s *SQLiteDB) KeepVersions(limit int) {
+	s.versionLimit = limit
+}
+
+// recordVersion stores doc as the newest version of its Drive file unless
+// its content is unchanged since the latest version. Any Drive revisions
+// that came with it are stored first, as they are older. previous is the
+// copy being replaced, if any: a document saved before versions were kept
+// has it recorded first, so its old content is not lost.
+func (s *SQLiteDB) recordVersion(ctx context.Context, queries *pipeline.Queries, previous *pipeline.Document, doc *models.Document, contentHash string) error {
+	stored, err := queries.ListVersionChecksums(ctx, doc.DriveFileID)
+	if err != nil {
+		return fmt.Errorf("failed to load versions: %w", err)
+	}
+
+	var next int64 = 1
+	for _, v := range stored {
+		next = max(next, v.Version+1)
+	}
+
+	addVersion := func(filepath, content, checksum, modified string) (int64, error) {
+		id, err := queries.CreateDocumentVersion(ctx, pipeline.CreateDocumentVersionParams{
+			DriveFileID:  doc.DriveFileID,
+			Version:      next,
+			Filepath:     filepath,
+			Content:      content,
+			Md5Checksum:  checksum,
+			LastModified: modified,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to save version %d: %w", next, err)
+		}
+		stored = append(stored, pipeline.ListVersionChecksumsRow{ID: id, Version: next, Md5Checksum: checksum})
+		next++
+		return id, nil
+	}
+
+	if len(stored) == 0 && previous != nil && previous.Content != doc.Content {
+		if _, err := addVersion(previous.Filepath, previous.Content, md5Hex(previous.Content), previous.LastModified); err != nil {
+			return err
+		}
+	}
+
+	for _, rev := range doc.Revisions {
+		if err := s.recordRevision(ctx, queries, rev, doc.FilePath, &stored, addVersion); err != nil {
+			return err
+		}
+	}
+
+	latest, err := queries.GetLatestDocumentVersion(ctx, doc.DriveFileID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to load latest version: %w", err)
+	}
+	if err != nil || latest.Md5Checksum != contentHash || latest.Filepath != doc.FilePath {
+		if _, err := addVersion(doc.FilePath, doc.Content, contentHash, doc.LastModified); err != nil {
+			return err
+		}
+	}
+
+	if s.versionLimit > 0 {
+		err := queries.PruneDocumentVersions(ctx, pipeline.PruneDocumentVersionsParams{
+			DriveFileID: doc.DriveFileID,
+			Limit:       int64(s.versionLimit),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to prune old versions: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// recordRevision stores a Drive revision as a version. A revision already
+// stored is skipped, and one whose content matches a version that has no
+// revision yet is attached to that version instead of duplicating it.
+func (s *SQLiteDB) recordRevision(ctx context.Context, queries *pipeline.Queries, rev models.Revision, filepath string,
+	stored *[]pipeline.ListVersionChecksumsRow, addVersion func(filepath, content, checksum, modified string) (int64, error)) error {
+	checksum := rev.ContentHash
+	if checksum == "" {
+		checksum = md5Hex(rev.Content)
+	}
+
+	var versionID int64
+	for i, v := range *stored {
+		if v.RevisionID == rev.ID {
+			return nil
+		}
+		if versionID == 0 && v.RevisionID == "" && v.Md5Checksum == checksum {
+			versionID = v.ID
+			(*stored)[i].RevisionID = rev.ID
+		}
+	}
+
+	if versionID == 0 {
+		id, err := addVersion(filepath, rev.Content, checksum, rev.ModifiedTime)
+		if err != nil {
+			return err
+		}
+		versionID = id
+		(*stored)[len(*stored)-1].RevisionID = rev.ID
+	}
+
+	err := queries.CreateVersionRevision(ctx, pipeline.CreateVersionRevisionParams{
+		VersionID:  versionID,
+		RevisionID: rev.ID,
+		Author:     rev.Author,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save revision %s: %w", rev.ID, err)
+	}
+	return nil
+}
+
+// DocumentHistory lists the stored versions of a Drive file from oldest to
+// newest by modification time. The last one is the current content.
+func (s *SQLiteDB) DocumentHistory(ctx context.Context, driveFileID string) ([]pipeline.ListDocumentVersionsRow, error) {
+	versions, err := s.queries.ListDocumentVersions(ctx, driveFileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+	return versions, nil
+}
+
+func (s *SQLiteDB) DocumentVersion(ctx context.Context, driveFileID string, version int64) (pipeline.DocumentVersion, error) {
+	v, err := s.queries.GetDocumentVersion(ctx, pipeline.GetDocumentVersionParams{
+		DriveFileID: driveFileID,
+		Version:     version,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return v, fmt.Errorf("no version %d of this document", version)
+	}
+	if err != nil {
+		return v, fmt.Errorf("failed to load version %d: %w", version, err)
+	}
+	return v, nil
+}
+
+func md5Hex(content string) string {
+	sum := md5.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,202 @@
+// Package summary picks the sentences that best sum up a document, ranking
+// them the way TextRank does: sentences that share words with many other
+// sentences are central to the text.
+package summary
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"injestion-pipeline/language"
+)
+
+// DefaultSentences is how many sentences a summary has.
+const DefaultSentences = 2
+
+const (
+	// maxSentences bounds the similarity graph, which grows with the
+	// square of the sentence count; later sentences are not considered.
+	maxSentences = 300
+	// minWords is how many words a sentence needs to be worth picking, so
+	// that headings and labels are left out.
+	minWords = 4
+	// maxSentenceRunes is where a long sentence is cut off in a summary.
+	maxSentenceRunes = 300
+
+	damping    = 0.85
+	iterations = 50
+	tolerance  = 1e-6
+)
+
+var (
+	sentenceEnd = regexp.MustCompile(`[.!?]+["')\]]*\s+`)
+	listMarker  = regexp.MustCompile(`^\s*(?:[-*+>]|\d+[.)])\s+`)
+)
+
+type sentence struct {
+	text  string
+	words map[string]bool
+	count int
+}
+
+// Summarize returns up to n sentences of text that share the most words
+// with the rest of it, in the order they appear. It returns an empty
+// string when text has no sentence of a few words.
+func Summarize(text string, n int) string {
+	sentences := split(text)
+	if len(sentences) == 0 || n <= 0 {
+		return ""
+	}
+
+	picked := make([]int, 0, len(sentences))
+	for i := range sentences {
+		picked = append(picked, i)
+	}
+	if len(sentences) > n {
+		scores := rank(sentences)
+		sort.SliceStable(picked, func(a, b int) bool {
+			return scores[picked[a]] > scores[picked[b]]
+		})
+		picked = picked[:n]
+		sort.Ints(picked)
+	}
+
+	parts := make([]string, 0, len(picked))
+	for _, i := range picked {
+		parts = append(parts, truncate(sentences[i].text))
+	}
+	return strings.Join(parts, " ")
+}
+
+// split breaks text into the sentences of its paragraphs. Lines of a
+// paragraph are joined, and markdown headings, tables and code blocks are
+// skipped, since they are not prose.
+func split(text string) []sentence {
+	var sentences []sentence
+	var paragraph []string
+	inCode := false
+
+	flush := func() {
+		joined := strings.Join(paragraph, " ")
+		paragraph = paragraph[:0]
+		for _, s := range splitSentences(joined) {
+			if len(sentences) >= maxSentences {
+				return
+			}
+			words := make(map[string]bool)
+			count := 0
+			for _, w := range language.Words(s) {
+				count++
+				if !language.IsStopword(w) {
+					words[w] = true
+				}
+			}
+			if count >= minWords && len(words) > 0 {
+				sentences = append(sentences, sentence{text: s, words: words, count: count})
+			}
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			inCode = !inCode
+		case inCode:
+		case trimmed == "", strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, "|"):
+			flush()
+		case listMarker.MatchString(line):
+			flush()
+			paragraph = append(paragraph, listMarker.ReplaceAllString(line, ""))
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	return sentences
+}
+
+// splitSentences splits a paragraph after each run of sentence-ending
+// punctuation that is followed by space.
+func splitSentences(paragraph string) []string {
+	var sentences []string
+	start := 0
+	for _, loc := range sentenceEnd.FindAllStringIndex(paragraph, -1) {
+		if s := strings.TrimSpace(paragraph[start:loc[1]]); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = loc[1]
+	}
+	if s := strings.TrimSpace(paragraph[start:]); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+// rank scores sentences by PageRank over a graph weighted by how many
+// words each pair shares, normalised by their lengths as in TextRank.
+func rank(sentences []sentence) []float64 {
+	n := len(sentences)
+	weights := make([][]float64, n)
+	outgoing := make([]float64, n)
+	for i := range weights {
+		weights[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			w := similarity(sentences[i], sentences[j])
+			weights[i][j], weights[j][i] = w, w
+			outgoing[i] += w
+			outgoing[j] += w
+		}
+	}
+
+	scores := make([]float64, n)
+	for i := range scores {
+		scores[i] = 1
+	}
+	next := make([]float64, n)
+	for range iterations {
+		delta := 0.0
+		for i := 0; i < n; i++ {
+			sum := 0.0
+			for j := 0; j < n; j++ {
+				if weights[j][i] > 0 {
+					sum += weights[j][i] / outgoing[j] * scores[j]
+				}
+			}
+			next[i] = 1 - damping + damping*sum
+			delta += math.Abs(next[i] - scores[i])
+		}
+		scores, next = next, scores
+		if delta < tolerance {
+			break
+		}
+	}
+	return scores
+}
+
+func similarity(a, b sentence) float64 {
+	shared := 0
+	for w := range a.words {
+		if b.words[w] {
+			shared++
+		}
+	}
+	if shared == 0 {
+		return 0
+	}
+	return float64(shared) / (math.Log(float64(a.count)) + math.Log(float64(b.count)))
+}
+
+func truncate(s string) string {
+	if utf8.RuneCountInString(s) <= maxSentenceRunes {
+		return s
+	}
+	return strings.TrimSpace(string([]rune(s)[:maxSentenceRunes])) + "..."
+}
@@ -0,0 +1,139 @@
+package vectorindex
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+)
+
+// fileVersion is bumped whenever the on-disk layout changes. Files with a
+// different version are refused, and the caller is expected to rebuild.
+const fileVersion = 1
+
+type fileHeader struct {
+	Version  int
+	Model    string
+	Dims     int
+	Config   Config
+	Entry    int64
+	MaxLevel int
+	Nodes    int
+}
+
+type fileNode struct {
+	ID      int64
+	Vector  []float32
+	Level   int
+	Links   [][]int64
+	Deleted bool
+}
+
+// Save writes the index to path. The file is written next to path first and
+// renamed into place, so a crash never leaves a half-written index behind.
+func (h *HNSW) Save(path string) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create index file: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	w := bufio.NewWriter(f)
+	enc := gob.NewEncoder(w)
+
+	err = enc.Encode(fileHeader{
+		Version:  fileVersion,
+		Model:    h.model,
+		Dims:     h.dims,
+		Config:   h.cfg,
+		Entry:    h.entry,
+		MaxLevel: h.maxLevel,
+		Nodes:    len(h.nodes),
+	})
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write index header: %w", err)
+	}
+
+	for _, n := range h.nodes {
+		err := enc.Encode(fileNode{
+			ID:      n.id,
+			Vector:  n.vector,
+			Level:   n.level,
+			Links:   n.links,
+			Deleted: n.deleted,
+		})
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write index node %d: %w", n.id, err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write index file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write index file: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace index file: %w", err)
+	}
+	return nil
+}
+
+// Load reads an index written by Save.
+func Load(path string) (*HNSW, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index file: %w", err)
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(bufio.NewReader(f))
+
+	var header fileHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, fmt.Errorf("failed to read index header: %w", err)
+	}
+	if header.Version != fileVersion {
+		return nil, fmt.Errorf("index file has version %d, expected %d", header.Version, fileVersion)
+	}
+
+	h := &HNSW{
+		model:     header.Model,
+		dims:      header.Dims,
+		cfg:       header.Config,
+		levelMult: 1 / math.Log(float64(header.Config.M)),
+		rng:       rand.New(rand.NewSource(int64(header.Nodes) + 1)),
+		nodes:     make(map[int64]*node, header.Nodes),
+		entry:     header.Entry,
+		maxLevel:  header.MaxLevel,
+	}
+
+	for range header.Nodes {
+		var fn fileNode
+		if err := dec.Decode(&fn); err != nil {
+			return nil, fmt.Errorf("failed to read index node: %w", err)
+		}
+		h.nodes[fn.ID] = &node{
+			id:      fn.ID,
+			vector:  fn.Vector,
+			level:   fn.Level,
+			links:   fn.Links,
+			deleted: fn.Deleted,
+		}
+		if fn.Deleted {
+			h.deleted++
+		}
+	}
+
+	return h, nil
+}
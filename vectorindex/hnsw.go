@@ -0,0 +1,417 @@
+package vectorindex
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// Config tunes the graph. M is the number of links each node keeps per
+// layer (twice that on the bottom layer), EfConstruction the breadth of
+// the search used to pick those links, and EfSearch the breadth of query
+// searches. Larger values trade speed and memory for recall.
+type Config struct {
+	M              int
+	EfConstruction int
+	EfSearch       int
+}
+
+var DefaultConfig = Config{
+	M:              16,
+	EfConstruction: 200,
+	EfSearch:       64,
+}
+
+// Neighbor is a search hit.
+type Neighbor struct {
+	ID         int64
+	Similarity float64
+}
+
+type node struct {
+	id      int64
+	vector  []float32
+	level   int
+	links   [][]int64
+	deleted bool
+}
+
+// HNSW is a hierarchical navigable small world graph over unit vectors,
+// searched by cosine similarity. Removal only marks a node as deleted, so
+// the graph stays navigable; Rebuild-style compaction is up to the caller.
+type HNSW struct {
+	mu sync.RWMutex
+
+	model     string
+	dims      int
+	cfg       Config
+	levelMult float64
+	rng       *rand.Rand
+
+	nodes    map[int64]*node
+	entry    int64
+	maxLevel int
+	deleted  int
+}
+
+func New(model string, dims int, cfg Config) *HNSW {
+	if cfg.M < 2 {
+		cfg.M = DefaultConfig.M
+	}
+	if cfg.EfConstruction < cfg.M {
+		cfg.EfConstruction = max(DefaultConfig.EfConstruction, cfg.M)
+	}
+	if cfg.EfSearch <= 0 {
+		cfg.EfSearch = DefaultConfig.EfSearch
+	}
+
+	return &HNSW{
+		model:     model,
+		dims:      dims,
+		cfg:       cfg,
+		levelMult: 1 / math.Log(float64(cfg.M)),
+		rng:       rand.New(rand.NewSource(1)),
+		nodes:     make(map[int64]*node),
+		maxLevel:  -1,
+	}
+}
+
+// Model is the embedding model whose vectors the index holds.
+func (h *HNSW) Model() string {
+	return h.model
+}
+
+func (h *HNSW) Dims() int {
+	return h.dims
+}
+
+// Len returns the number of live, searchable vectors.
+func (h *HNSW) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.nodes) - h.deleted
+}
+
+// Deleted returns the number of removed vectors still held in the graph.
+func (h *HNSW) Deleted() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.deleted
+}
+
+// Add inserts a vector, replacing any earlier vector with the same id.
+func (h *HNSW) Add(id int64, vector []float32) error {
+	if len(vector) != h.dims {
+		return fmt.Errorf("vector has %d dimensions, index expects %d", len(vector), h.dims)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.nodes[id]; ok {
+		h.removeLocked(id)
+		h.purgeLocked(id)
+	}
+
+	n := &node{
+		id:     id,
+		vector: unit(vector),
+		level:  h.randomLevel(),
+	}
+	n.links = make([][]int64, n.level+1)
+	h.nodes[id] = n
+
+	if h.maxLevel < 0 {
+		h.entry = id
+		h.maxLevel = n.level
+		return nil
+	}
+
+	ep := h.entry
+	for level := h.maxLevel; level > n.level; level-- {
+		ep = h.greedy(n.vector, ep, level)
+	}
+
+	for level := min(n.level, h.maxLevel); level >= 0; level-- {
+		candidates := h.searchLayer(n.vector, []int64{ep}, h.cfg.EfConstruction, level)
+		neighbors := closest(candidates, h.maxLinks(level))
+
+		for _, c := range neighbors {
+			n.links[level] = append(n.links[level], c.id)
+			h.link(c.id, id, level)
+		}
+		if len(candidates) > 0 {
+			ep = candidates[0].id
+		}
+	}
+
+	if n.level > h.maxLevel {
+		h.entry = id
+		h.maxLevel = n.level
+	}
+
+	return nil
+}
+
+// Remove marks a vector as deleted. It is no longer returned by Search but
+// keeps routing searches through the graph until the index is rebuilt.
+func (h *HNSW) Remove(id int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(id)
+}
+
+func (h *HNSW) removeLocked(id int64) {
+	if n, ok := h.nodes[id]; ok && !n.deleted {
+		n.deleted = true
+		h.deleted++
+	}
+}
+
+// purgeLocked drops a deleted node outright, so its id can be reused. Links
+// pointing at it are removed; the entry point moves if it was the node.
+func (h *HNSW) purgeLocked(id int64) {
+	n, ok := h.nodes[id]
+	if !ok {
+		return
+	}
+	if n.deleted {
+		h.deleted--
+	}
+	delete(h.nodes, id)
+
+	for _, other := range h.nodes {
+		for level := range other.links {
+			other.links[level] = removeID(other.links[level], id)
+		}
+	}
+
+	if h.entry == id {
+		h.maxLevel = -1
+		for _, other := range h.nodes {
+			if other.level > h.maxLevel {
+				h.entry = other.id
+				h.maxLevel = other.level
+			}
+		}
+	}
+}
+
+// Search returns up to k live vectors most similar to query, best first.
+func (h *HNSW) Search(query []float32, k int) []Neighbor {
+	if len(query) != h.dims || k <= 0 {
+		return nil
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.maxLevel < 0 {
+		return nil
+	}
+
+	q := unit(query)
+	ep := h.entry
+	for level := h.maxLevel; level > 0; level-- {
+		ep = h.greedy(q, ep, level)
+	}
+
+	candidates := h.searchLayer(q, []int64{ep}, max(h.cfg.EfSearch, k), 0)
+
+	var out []Neighbor
+	for _, c := range candidates {
+		if h.nodes[c.id].deleted {
+			continue
+		}
+		out = append(out, Neighbor{ID: c.id, Similarity: 1 - c.dist})
+		if len(out) == k {
+			break
+		}
+	}
+	return out
+}
+
+// greedy walks from ep towards query on one layer until no neighbour is
+// closer.
+func (h *HNSW) greedy(query []float32, ep int64, level int) int64 {
+	best := ep
+	bestDist := distance(query, h.nodes[ep].vector)
+
+	for changed := true; changed; {
+		changed = false
+		for _, id := range h.nodes[best].linksAt(level) {
+			if d := distance(query, h.nodes[id].vector); d < bestDist {
+				best, bestDist = id, d
+				changed = true
+			}
+		}
+	}
+
+	return best
+}
+
+// searchLayer is the beam search from the HNSW paper. It returns up to ef
+// nodes sorted by increasing distance.
+func (h *HNSW) searchLayer(query []float32, entries []int64, ef int, level int) []candidate {
+	visited := make(map[int64]bool, ef*4)
+	toVisit := &minHeap{}
+	found := &maxHeap{}
+
+	for _, id := range entries {
+		c := candidate{id: id, dist: distance(query, h.nodes[id].vector)}
+		visited[id] = true
+		heap.Push(toVisit, c)
+		heap.Push(found, c)
+	}
+
+	for toVisit.Len() > 0 {
+		current := heap.Pop(toVisit).(candidate)
+		if found.Len() >= ef && current.dist > (*found)[0].dist {
+			break
+		}
+
+		for _, id := range h.nodes[current.id].linksAt(level) {
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+
+			c := candidate{id: id, dist: distance(query, h.nodes[id].vector)}
+			if found.Len() < ef || c.dist < (*found)[0].dist {
+				heap.Push(toVisit, c)
+				heap.Push(found, c)
+				if found.Len() > ef {
+					heap.Pop(found)
+				}
+			}
+		}
+	}
+
+	out := make([]candidate, found.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(found).(candidate)
+	}
+	return out
+}
+
+// link adds a one-way edge from -> to, pruning from's links back to the
+// closest maxLinks when it has too many.
+func (h *HNSW) link(from, to int64, level int) {
+	n := h.nodes[from]
+	for len(n.links) <= level {
+		n.links = append(n.links, nil)
+	}
+	n.links[level] = append(n.links[level], to)
+
+	limit := h.maxLinks(level)
+	if len(n.links[level]) <= limit {
+		return
+	}
+
+	candidates := make([]candidate, 0, len(n.links[level]))
+	for _, id := range n.links[level] {
+		candidates = append(candidates, candidate{id: id, dist: distance(n.vector, h.nodes[id].vector)})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+
+	n.links[level] = n.links[level][:0]
+	for _, c := range candidates[:limit] {
+		n.links[level] = append(n.links[level], c.id)
+	}
+}
+
+func (h *HNSW) maxLinks(level int) int {
+	if level == 0 {
+		return 2 * h.cfg.M
+	}
+	return h.cfg.M
+}
+
+func (h *HNSW) randomLevel() int {
+	return int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+}
+
+func (n *node) linksAt(level int) []int64 {
+	if level < len(n.links) {
+		return n.links[level]
+	}
+	return nil
+}
+
+type candidate struct {
+	id   int64
+	dist float64
+}
+
+func closest(candidates []candidate, n int) []candidate {
+	if len(candidates) > n {
+		return candidates[:n]
+	}
+	return candidates
+}
+
+// distance is the cosine distance between two unit vectors.
+func distance(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return 1 - dot
+}
+
+func unit(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+
+	out := make([]float32, len(v))
+	if sum == 0 {
+		return out
+	}
+
+	norm := math.Sqrt(sum)
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+func removeID(ids []int64, id int64) []int64 {
+	out := ids[:0]
+	for _, other := range ids {
+		if other != id {
+			out = append(out, other)
+		}
+	}
+	return out
+}
+
+type minHeap []candidate
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i].dist < h[j].dist }
+func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+type maxHeap []candidate
+
+func (h maxHeap) Len() int           { return len(h) }
+func (h maxHeap) Less(i, j int) bool { return h[i].dist > h[j].dist }
+func (h maxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
@@ -0,0 +1,220 @@
+package vectorindex
+
+import (
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func randomVectors(rng *rand.Rand, n, dims int) [][]float32 {
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		v := make([]float32, dims)
+		for j := range v {
+			v[j] = float32(rng.NormFloat64())
+		}
+		vectors[i] = v
+	}
+	return vectors
+}
+
+func buildIndex(t *testing.T, vectors [][]float32) *HNSW {
+	t.Helper()
+	h := New("test", len(vectors[0]), DefaultConfig)
+	for i, v := range vectors {
+		if err := h.Add(int64(i), v); err != nil {
+			t.Fatalf("Add(%d) failed: %v", i, err)
+		}
+	}
+	return h
+}
+
+// bruteForce returns the ids of the k vectors most similar to query,
+// skipping those in removed.
+func bruteForce(query []float32, vectors [][]float32, k int, removed map[int64]bool) []int64 {
+	q := unit(query)
+	type scored struct {
+		id   int64
+		dist float64
+	}
+	var all []scored
+	for i, v := range vectors {
+		if removed[int64(i)] {
+			continue
+		}
+		all = append(all, scored{int64(i), distance(q, unit(v))})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].dist < all[j].dist })
+
+	ids := make([]int64, 0, k)
+	for _, s := range all[:min(k, len(all))] {
+		ids = append(ids, s.id)
+	}
+	return ids
+}
+
+func recall(h *HNSW, queries, vectors [][]float32, k int, removed map[int64]bool) float64 {
+	var found, wanted int
+	for _, q := range queries {
+		returned := make(map[int64]bool)
+		for _, n := range h.Search(q, k) {
+			returned[n.ID] = true
+		}
+		for _, id := range bruteForce(q, vectors, k, removed) {
+			if returned[id] {
+				found++
+			}
+		}
+		wanted += k
+	}
+	return float64(found) / float64(wanted)
+}
+
+func TestSearchRecall(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	vectors := randomVectors(rng, 2000, 32)
+	queries := randomVectors(rng, 50, 32)
+	h := buildIndex(t, vectors)
+
+	if got := h.Len(); got != len(vectors) {
+		t.Fatalf("Len() = %d, want %d", got, len(vectors))
+	}
+	if r := recall(h, queries, vectors, 10, nil); r < 0.95 {
+		t.Errorf("recall@10 = %.3f, want at least 0.95", r)
+	}
+}
+
+func TestSearchOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	vectors := randomVectors(rng, 300, 16)
+	h := buildIndex(t, vectors)
+
+	hits := h.Search(vectors[17], 10)
+	if len(hits) != 10 {
+		t.Fatalf("Search returned %d hits, want 10", len(hits))
+	}
+	if hits[0].ID != 17 || hits[0].Similarity < 0.999 {
+		t.Errorf("best hit = %+v, want the query vector itself", hits[0])
+	}
+	for i := 1; i < len(hits); i++ {
+		if hits[i].Similarity > hits[i-1].Similarity {
+			t.Errorf("hit %d (%.4f) ranks above hit %d (%.4f)", i, hits[i].Similarity, i-1, hits[i-1].Similarity)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	vectors := randomVectors(rng, 1000, 32)
+	queries := randomVectors(rng, 30, 32)
+	h := buildIndex(t, vectors)
+
+	removed := make(map[int64]bool)
+	for id := int64(0); id < int64(len(vectors)); id += 3 {
+		h.Remove(id)
+		removed[id] = true
+	}
+	// Removing twice, or an id never added, changes nothing.
+	h.Remove(0)
+	h.Remove(99999)
+
+	if got, want := h.Deleted(), len(removed); got != want {
+		t.Errorf("Deleted() = %d, want %d", got, want)
+	}
+	if got, want := h.Len(), len(vectors)-len(removed); got != want {
+		t.Errorf("Len() = %d, want %d", got, want)
+	}
+
+	for _, q := range append(queries, vectors[0], vectors[3]) {
+		for _, n := range h.Search(q, 20) {
+			if removed[n.ID] {
+				t.Fatalf("Search returned removed vector %d", n.ID)
+			}
+		}
+	}
+	if r := recall(h, queries, vectors, 10, removed); r < 0.95 {
+		t.Errorf("recall@10 after removal = %.3f, want at least 0.95", r)
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	vectors := randomVectors(rng, 50, 8)
+	h := buildIndex(t, vectors)
+
+	for i := range vectors {
+		h.Remove(int64(i))
+	}
+	if h.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", h.Len())
+	}
+	if hits := h.Search(vectors[0], 5); len(hits) != 0 {
+		t.Errorf("Search returned %v from an index with every vector removed", hits)
+	}
+}
+
+func TestAddReplaces(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	vectors := randomVectors(rng, 200, 16)
+	h := buildIndex(t, vectors)
+
+	h.Remove(5)
+	if err := h.Add(5, vectors[100]); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if h.Len() != len(vectors) || h.Deleted() != 0 {
+		t.Errorf("Len() = %d, Deleted() = %d, want %d and 0", h.Len(), h.Deleted(), len(vectors))
+	}
+
+	hits := h.Search(vectors[100], 2)
+	ids := map[int64]bool{}
+	for _, n := range hits {
+		ids[n.ID] = true
+	}
+	if !ids[5] || !ids[100] {
+		t.Errorf("Search for the replaced vector = %v, want ids 5 and 100", hits)
+	}
+}
+
+func TestAddDimensionMismatch(t *testing.T) {
+	h := New("test", 4, DefaultConfig)
+	if err := h.Add(1, []float32{1, 2, 3}); err == nil {
+		t.Error("Add accepted a vector of the wrong length")
+	}
+	if hits := h.Search([]float32{1, 2, 3}, 1); hits != nil {
+		t.Errorf("Search with the wrong length = %v, want nil", hits)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	vectors := randomVectors(rng, 300, 16)
+	h := buildIndex(t, vectors)
+	h.Remove(7)
+
+	path := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := h.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if loaded.Model() != "test" || loaded.Dims() != 16 || loaded.Len() != h.Len() || loaded.Deleted() != 1 {
+		t.Errorf("loaded index = %s/%d with %d live and %d deleted, want test/16 with %d and 1",
+			loaded.Model(), loaded.Dims(), loaded.Len(), loaded.Deleted(), h.Len())
+	}
+	for _, q := range randomVectors(rng, 10, 16) {
+		want, got := h.Search(q, 5), loaded.Search(q, 5)
+		if len(want) != len(got) {
+			t.Fatalf("loaded index returned %d hits, want %d", len(got), len(want))
+		}
+		for i := range want {
+			if want[i].ID != got[i].ID {
+				t.Errorf("hit %d = %d, want %d", i, got[i].ID, want[i].ID)
+			}
+		}
+	}
+}